@@ -0,0 +1,181 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Overflow policies of AsyncConfig
+const (
+	OverflowBlock = "block"
+	OverflowDrop  = "drop"
+)
+
+// AsyncConfig buffers the writes of the file and stdout core, written by a
+// goroutine once a buffer is full, every FlushInterval, on Sync and on the
+// entries above error. A write waits for the goroutine or is dropped by
+// Overflow if both buffers are full.
+type AsyncConfig struct {
+	BufferSize    int    // K, default 256
+	FlushInterval int    // ms, default 1000
+	Overflow      string // block/drop, default block
+}
+
+// asyncWriter is a WriteSyncer double buffered, the filled buffer is
+// written by run while the other one is filled.
+type asyncWriter struct {
+	ws       zapcore.WriteSyncer
+	size     int
+	drop     bool
+	mu       sync.Mutex
+	buf      []byte
+	closed   bool
+	full     chan []byte // to be written, at most one
+	free     chan []byte // the spare buffer
+	done     chan struct{}
+	stop     chan struct{}
+	dropped  uint64
+	reported uint64
+}
+
+func newAsyncWriter(ws zapcore.WriteSyncer, config *AsyncConfig) (*asyncWriter, error) {
+	size, interval := 256*1024, time.Second
+	if config.BufferSize > 0 {
+		size = config.BufferSize * 1024
+	}
+	if config.FlushInterval > 0 {
+		interval = time.Duration(config.FlushInterval) * time.Millisecond
+	}
+	switch config.Overflow {
+	case "", OverflowBlock, OverflowDrop:
+	default:
+		return nil, fmt.Errorf("unknown overflow policy %q", config.Overflow)
+	}
+
+	w := &asyncWriter{
+		ws:   ws,
+		size: size,
+		drop: config.Overflow == OverflowDrop,
+		buf:  make([]byte, 0, size),
+		full: make(chan []byte, 1),
+		free: make(chan []byte, 1),
+		done: make(chan struct{}),
+		stop: make(chan struct{}),
+	}
+	w.free <- make([]byte, 0, size)
+	go w.run()
+	go w.tick(interval)
+	return w, nil
+}
+
+// Write copies p into the buffer, swapping it once full.
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return w.ws.Write(p)
+	}
+	if len(w.buf) > 0 && len(w.buf)+len(p) > w.size && !w.swap(!w.drop) {
+		atomic.AddUint64(&w.dropped, 1)
+		return len(p), nil
+	}
+	w.buf = append(w.buf, p...)
+	return len(p), nil
+}
+
+// swap passes the buffer to run for the spare one, false if run is still
+// writing the spare one and block is false. w.mu is held.
+func (w *asyncWriter) swap(block bool) bool {
+	var spare []byte
+	select {
+	case spare = <-w.free:
+	default:
+		if !block {
+			return false
+		}
+		spare = <-w.free
+	}
+	w.full <- w.buf
+	w.buf = spare
+	return true
+}
+
+// Sync writes the buffers and syncs the underlying writer.
+func (w *asyncWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.flush()
+	}
+	return w.ws.Sync()
+}
+
+// flush passes the buffer to run and waits for it to be written. w.mu is
+// held.
+func (w *asyncWriter) flush() {
+	if len(w.buf) > 0 {
+		w.swap(true)
+	}
+	spare := <-w.free
+	w.free <- spare
+}
+
+// Close writes the buffers and stops the goroutines, later writes are not
+// buffered.
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.flush()
+	w.closed = true
+	close(w.full)
+	close(w.stop)
+	w.mu.Unlock()
+
+	<-w.done
+	return w.ws.Sync()
+}
+
+// Dropped returns the writes dropped by the overflow policy.
+func (w *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for buf := range w.full {
+		if _, err := w.ws.Write(buf); err != nil {
+			fmt.Fprintf(os.Stderr, "[log] async write err: %v\n", err)
+		}
+		w.free <- buf[:0]
+	}
+}
+
+// tick passes the buffer to run every interval unless run is busy, the
+// buffer is then passed by the next Write over its size.
+func (w *asyncWriter) tick(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			w.mu.Lock()
+			if !w.closed && len(w.buf) > 0 {
+				w.swap(false)
+			}
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
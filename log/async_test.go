@@ -0,0 +1,172 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// gateSyncer writes to buf once the gate is open.
+type gateSyncer struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (s *gateSyncer) Write(p []byte) (int, error) {
+	<-s.gate
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *gateSyncer) Sync() error { return nil }
+
+func (s *gateSyncer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	s := &gateSyncer{gate: make(chan struct{})}
+	close(s.gate)
+	w, err := newAsyncWriter(s, &AsyncConfig{FlushInterval: 60000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.Write([]byte("a\n"))
+	w.Write([]byte("b\n"))
+	if s.String() != "" {
+		t.Fatal("written before Sync")
+	}
+	w.Sync()
+	if get := s.String(); get != "a\nb\n" {
+		t.Fatalf("synced %q", get)
+	}
+	w.Write([]byte("c\n"))
+	w.Close()
+	w.Write([]byte("d\n"))
+	if get := s.String(); get != "a\nb\nc\nd\n" {
+		t.Errorf("closed %q", get)
+	}
+
+	if _, err = newAsyncWriter(s, &AsyncConfig{Overflow: "nope"}); err == nil {
+		t.Error("unknown overflow")
+	}
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	s := &gateSyncer{gate: make(chan struct{})}
+	w, err := newAsyncWriter(s, &AsyncConfig{BufferSize: 1, FlushInterval: 60000, Overflow: OverflowDrop})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first buffer is stuck in the gate, the second one fills up
+	line := []byte(strings.Repeat("x", 511) + "\n")
+	for i := 0; i < 8; i++ {
+		w.Write(line)
+	}
+	if dropped := w.Dropped(); dropped != 4 {
+		t.Errorf("dropped %d", dropped)
+	}
+
+	close(s.gate)
+	w.Close()
+	if n := strings.Count(s.String(), "\n"); n != 4 {
+		t.Errorf("written %d", n)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	dir, err := ioutil.TempDir("", "async")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	l, err := New(&Config{
+		Path:          path,
+		MaxSize:       10,
+		Format:        "logfmt",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Async:         &AsyncConfig{FlushInterval: 60000},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func() string {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	l.Info("one")
+	if read() != "" {
+		t.Fatal("written before Sync")
+	}
+	l.Sync()
+	if get := read(); get != "level=INFO msg=one\n" {
+		t.Fatalf("synced %q", get)
+	}
+	l.Info("two")
+	if err = l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if get := read(); get != "level=INFO msg=one\nlevel=INFO msg=two\n" {
+		t.Errorf("closed %q", get)
+	}
+}
+
+func benchmarkFile(b *testing.B, async *AsyncConfig) {
+	dir, err := ioutil.TempDir("", "bench")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := New(&Config{
+		Path:          filepath.Join(dir, "app.log"),
+		MaxSize:       100,
+		Format:        "json",
+		DisableStdout: true,
+		Async:         async,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer l.Close(context.Background())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Info("order filled", zap.String("symbol", "EURUSD"), zap.Float64("price", 1.0842), zap.Int("qty", 100))
+		}
+	})
+}
+
+func BenchmarkFileSync(b *testing.B) {
+	benchmarkFile(b, nil)
+}
+
+func BenchmarkFileAsync(b *testing.B) {
+	benchmarkFile(b, &AsyncConfig{})
+}
+
+func BenchmarkFileAsyncDrop(b *testing.B) {
+	benchmarkFile(b, &AsyncConfig{Overflow: OverflowDrop})
+}
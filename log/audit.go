@@ -0,0 +1,368 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/encoder"
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"github.com/hkjojo/go-toolkits/log/rotate"
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Audit record keys
+const (
+	AuditActionKey = "action"
+	AuditSeqKey    = "seq"
+	AuditPrevKey   = "prev"
+)
+
+var errNoAudit = errors.New("log: audit is not configured")
+
+// AuditConfig is the append-only audit trail written by Audit, apart from
+// the other cores. Each record is a JSON line with its seq, increased by
+// one, and the prev sha256 of the previous line, so a gap or a change is
+// reported by VerifyAudit. The chain goes on from the last line of Path
+// when it is opened again.
+type AuditConfig struct {
+	Path       string
+	MaxSize    int            // M
+	MaxBackups int            // max backup file num
+	MaxAge     int            // days
+	Compress   bool           // compress gz
+	RotateDay  int            // days
+	Rotate     *rotate.Config // instead of MaxSize and RotateDay
+	Sync       bool           // sync the file after each record
+}
+
+// auditCore writes the chained records, serialized.
+type auditCore struct {
+	enc      zapcore.Encoder
+	ws       zapcore.WriteSyncer
+	sync     bool
+	redactor *redact.Redactor
+
+	mu   sync.Mutex
+	seq  uint64
+	prev string
+}
+
+func newAuditCore(config *Config, cores *coreSet) (*auditCore, error) {
+	a := config.Audit
+	if a.Path == "" {
+		return nil, errors.New("log: audit has no path")
+	}
+	enc, err := encoder.New(encoder.FormatJSON, zapcore.EncoderConfig{
+		TimeKey:        "time",
+		MessageKey:     AuditActionKey,
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+	})
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &auditCore{enc: enc, sync: a.Sync, redactor: redactor}
+	if c.seq, c.prev, err = lastAuditRecord(a.Path); err != nil {
+		return nil, err
+	}
+
+	ws, closers, err := openFile(&OutputConfig{
+		Path:       a.Path,
+		MaxSize:    a.MaxSize,
+		MaxBackups: a.MaxBackups,
+		MaxAge:     a.MaxAge,
+		Compress:   a.Compress,
+		RotateDay:  a.RotateDay,
+		Rotate:     a.Rotate,
+	}, true)
+	cores.closers = append(cores.closers, closers...)
+	if err != nil {
+		return nil, err
+	}
+	c.ws = zapcore.NewMultiWriteSyncer(ws...)
+	return c, nil
+}
+
+// write writes the record of action, returning its seq.
+func (c *auditCore) write(action string, fields []zapcore.Field) (uint64, error) {
+	if c.redactor != nil {
+		fields = c.redactor.Fields(fields)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seq := c.seq + 1
+	chained := make([]zapcore.Field, 0, len(fields)+2)
+	chained = append(chained, zap.Uint64(AuditSeqKey, seq), zap.String(AuditPrevKey, c.prev))
+	chained = append(chained, fields...)
+	buf, err := c.enc.EncodeEntry(zapcore.Entry{Time: time.Now(), Message: action}, chained)
+	if err != nil {
+		return 0, err
+	}
+	defer buf.Free()
+
+	if _, err = c.ws.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	if c.sync {
+		if err = c.ws.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	c.seq, c.prev = seq, auditHash(bytes.TrimRight(buf.Bytes(), "\n"))
+	return seq, nil
+}
+
+func auditHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastAuditRecord returns the seq and hash of the last line of path, zeros
+// if there is none.
+func lastAuditRecord(path string) (uint64, string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+	// records are far below 1M, the last one is in the tail
+	const tail = 1024 * 1024
+	off := info.Size() - tail
+	if off < 0 {
+		off = 0
+	}
+	data := make([]byte, info.Size()-off)
+	if _, err = f.ReadAt(data, off); err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return 0, "", nil
+	}
+	line := data[bytes.LastIndexByte(data, '\n')+1:]
+	var record struct {
+		Seq uint64 `json:"seq"`
+	}
+	if err = json.Unmarshal(line, &record); err != nil {
+		return 0, "", fmt.Errorf("log: audit %s last record: %v", path, err)
+	}
+	return record.Seq, auditHash(line), nil
+}
+
+// Audit writes an audit record of action with the fields of the context
+// extractors and the key-value pairs, as they are in With. It returns once
+// written, with the error of the write. A Reload waits for it so the chain
+// goes on in the reopened file.
+func (log *Logger) Audit(ctx context.Context, action string, keysAndValues ...interface{}) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.cores == nil || log.cores.audit == nil {
+		return errNoAudit
+	}
+	c := log.cores.audit
+
+	var kvs []interface{}
+	if ctx != nil {
+		kvs = contextFields(ctx)
+	}
+	kvs = append(kvs, keysAndValues...)
+	_, err := c.write(action, auditFields(kvs))
+	return err
+}
+
+// Audit writes an audit record with the standard logger, see Logger.Audit.
+func Audit(ctx context.Context, action string, keysAndValues ...interface{}) error {
+	return logger.Audit(ctx, action, keysAndValues...)
+}
+
+// auditFields returns the fields of key-value pairs, a zapcore.Field is
+// taken as is.
+func auditFields(kvs []interface{}) []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(kvs)/2)
+	for i := 0; i < len(kvs); i++ {
+		if f, ok := kvs[i].(zapcore.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		if i+1 == len(kvs) {
+			fields = append(fields, zap.Any("ignored", kvs[i]))
+			break
+		}
+		fields = append(fields, zap.Any(fmt.Sprint(kvs[i]), kvs[i+1]))
+		i++
+	}
+	return fields
+}
+
+// AuditProblem is a gap or a tampered record found by VerifyAudit.
+type AuditProblem struct {
+	File   string
+	Line   int
+	Seq    uint64
+	Reason string
+}
+
+func (p AuditProblem) String() string {
+	return fmt.Sprintf("%s:%d seq %d: %s", p.File, p.Line, p.Seq, p.Reason)
+}
+
+// AuditReport is the result of VerifyAudit.
+type AuditReport struct {
+	Records  int
+	First    uint64 // seq of the first record
+	Last     uint64 // seq of the last record
+	Head     string // hash of the last record, to be kept apart to detect its change
+	Problems []AuditProblem
+}
+
+// OK reports whether no problem was found.
+func (r *AuditReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// verify checks the records of r named name, following the chain of the
+// report.
+func (r *AuditReport) verify(name string, rd io.Reader) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record struct {
+			Seq  *uint64 `json:"seq"`
+			Prev *string `json:"prev"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil || record.Seq == nil || record.Prev == nil {
+			r.Problems = append(r.Problems, AuditProblem{name, n, r.Last + 1, "bad record"})
+			continue
+		}
+
+		seq := *record.Seq
+		if r.Records == 0 {
+			r.First = seq
+			if seq == 1 && *record.Prev != "" {
+				r.Problems = append(r.Problems, AuditProblem{name, n, seq, "first record chained"})
+			}
+		} else {
+			switch {
+			case seq <= r.Last:
+				r.Problems = append(r.Problems, AuditProblem{name, n, seq,
+					fmt.Sprintf("seq after %d", r.Last)})
+			case seq > r.Last+1:
+				r.Problems = append(r.Problems, AuditProblem{name, n, seq,
+					fmt.Sprintf("gap of %d records after %d", seq-r.Last-1, r.Last)})
+			case *record.Prev != r.Head:
+				r.Problems = append(r.Problems, AuditProblem{name, n, seq, "previous record changed"})
+			}
+		}
+		r.Records++
+		r.Last, r.Head = seq, auditHash(line)
+	}
+	return scanner.Err()
+}
+
+// VerifyAudit walks the audit records of r and reports any gap or change.
+func VerifyAudit(r io.Reader) (*AuditReport, error) {
+	report := &AuditReport{}
+	return report, report.verify("", r)
+}
+
+// VerifyAuditFiles verifies the files as a single chain, ordered by the
+// seq of their first record, e.g. a file and its rotated ones. The files
+// ending with .gz or .zst are decompressed.
+func VerifyAuditFiles(names ...string) (*AuditReport, error) {
+	firsts := make(map[string]uint64, len(names))
+	for _, name := range names {
+		err := readAuditFile(name, func(r io.Reader) error {
+			scanner := bufio.NewScanner(r)
+			scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+			if scanner.Scan() {
+				var record struct {
+					Seq uint64 `json:"seq"`
+				}
+				json.Unmarshal(scanner.Bytes(), &record)
+				firsts[name] = record.Seq
+			}
+			return scanner.Err()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	sorted := append([]string(nil), names...)
+	sort.SliceStable(sorted, func(i, j int) bool { return firsts[sorted[i]] < firsts[sorted[j]] })
+
+	report := &AuditReport{}
+	for _, name := range sorted {
+		err := readAuditFile(name, func(r io.Reader) error {
+			return report.verify(name, r)
+		})
+		if err != nil {
+			return report, fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return report, nil
+}
+
+// readAuditFile calls fn with the content of name, decompressed by its
+// extension.
+func readAuditFile(name string, fn func(io.Reader) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		return fn(gr)
+	case strings.HasSuffix(name, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		return fn(zr)
+	}
+	return fn(f)
+}
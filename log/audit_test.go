@@ -0,0 +1,85 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	config := &Config{
+		Level:         "info",
+		DisableStdout: true,
+		Audit:         &AuditConfig{Path: path},
+	}
+	for i := 0; i < 2; i++ {
+		l, err := New(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, user := range []string{"bob", "alice"} {
+			if err = l.Audit(context.Background(), "login", "user", user); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = l.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the chain goes on after reopening
+	report, err := VerifyAuditFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || report.Records != 4 || report.First != 1 || report.Last != 4 {
+		t.Errorf("report %+v", report)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(strings.TrimRight(string(data), "\n"), "\n")
+	if !strings.Contains(lines[0], `"action":"login","seq":1,"prev":"","user":"bob"`) {
+		t.Errorf("record %s", lines[0])
+	}
+
+	tests := []struct {
+		name   string
+		lines  []string
+		reason string
+	}{
+		{"changed", []string{lines[0], strings.Replace(lines[1], "alice", "eve", 1), lines[2], lines[3]},
+			"previous record changed"},
+		{"deleted", []string{lines[0], lines[1], lines[3]}, "gap of 1 records after 2"},
+		{"swapped", []string{lines[0], lines[2], lines[1], lines[3]}, "gap of 1 records after 1"},
+	}
+	for _, tt := range tests {
+		report, err := VerifyAudit(strings.NewReader(strings.Join(tt.lines, "")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.OK() || report.Problems[0].Reason != tt.reason {
+			t.Errorf("%s: problems %v", tt.name, report.Problems)
+		}
+	}
+
+	l, err := New(&Config{DisableStdout: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = l.Audit(context.Background(), "login"); err != errNoAudit {
+		t.Errorf("audit without config %v", err)
+	}
+}
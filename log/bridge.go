@@ -0,0 +1,115 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// PrintLogger logs the Print, Printf and Println calls of a library at a
+// level, through the cores of its logger.
+type PrintLogger struct {
+	l     *zap.Logger
+	level zapcore.Level
+}
+
+// PrintLogger returns a PrintLogger named name writing at level, the
+// caller is the one of Print.
+func (log *Logger) PrintLogger(name, level string) *PrintLogger {
+	l := log.Logger.Named(name).WithOptions(zap.AddCallerSkip(2))
+	return &PrintLogger{l, hook.ParseLevel(level)}
+}
+
+func (p *PrintLogger) write(msg string) {
+	if ce := p.l.Check(p.level, strings.TrimRight(msg, "\n")); ce != nil {
+		ce.Write()
+	}
+}
+
+// Print ..
+func (p *PrintLogger) Print(v ...interface{}) {
+	p.write(fmt.Sprint(v...))
+}
+
+// Printf ..
+func (p *PrintLogger) Printf(format string, v ...interface{}) {
+	p.write(fmt.Sprintf(format, v...))
+}
+
+// Println ..
+func (p *PrintLogger) Println(v ...interface{}) {
+	p.write(fmt.Sprintln(v...))
+}
+
+// SaramaLogger returns the logger named sarama at info, to be set as
+// sarama.Logger.
+func (log *Logger) SaramaLogger() sarama.StdLogger {
+	return log.PrintLogger("sarama", "info")
+}
+
+// GormLogger logs the queries of gorm v1 at debug with their sql, vars,
+// duration, rows and source, its errors at error and its other messages at
+// info. It is set by sql.Config.Logger or gorm.DB.SetLogger.
+type GormLogger struct {
+	l *zap.Logger
+}
+
+// GormLogger returns the GormLogger named gorm.
+func (log *Logger) GormLogger() *GormLogger {
+	return &GormLogger{log.Logger.Named("gorm")}
+}
+
+// Print implements the logger of gorm v1, values are its level, source
+// then for the queries duration, sql, vars and rows.
+func (g *GormLogger) Print(values ...interface{}) {
+	if len(values) < 2 {
+		g.l.Info(fmt.Sprint(values...))
+		return
+	}
+
+	source := zap.String("source", fmt.Sprint(values[1]))
+	switch values[0] {
+	case "sql":
+		if len(values) < 6 {
+			break
+		}
+		duration, _ := values[2].(time.Duration)
+		rows, _ := values[5].(int64)
+		g.l.Debug("sql",
+			zap.String("sql", fmt.Sprint(values[3])),
+			zap.Any("vars", values[4]),
+			zap.Duration("duration", duration),
+			zap.Int64("rows", rows),
+			source,
+		)
+		return
+	case "error":
+		g.l.Error(fmt.Sprint(values[2:]...), source)
+		return
+	}
+	g.l.Info(fmt.Sprint(values[2:]...), source)
+}
+
+// RedirectStdLog writes the output of the stdlib log package at level with
+// the logger named stdlog, until the returned func restores it.
+func (log *Logger) RedirectStdLog(level string) (func(), error) {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, err
+	}
+	return zap.RedirectStdLogAt(log.Logger.Named("stdlog"), lvl)
+}
+
+// restoreStdLog restores the stdlib log redirected by Init.
+var restoreStdLog func()
+
+// RedirectStdLog redirects the stdlib log package to the standard logger,
+// see Logger.RedirectStdLog.
+func RedirectStdLog(level string) (func(), error) {
+	return logger.RedirectStdLog(level)
+}
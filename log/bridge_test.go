@@ -0,0 +1,66 @@
+package log
+
+import (
+	"errors"
+	stdlog "log"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/ring"
+	"go.uber.org/zap/zapcore"
+)
+
+func newRingLogger(t *testing.T) (*Logger, *ring.Core) {
+	l, err := New(&Config{Level: "debug", DisableStdout: true, Ring: &RingConfig{Size: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, l.Ring()
+}
+
+func TestBridges(t *testing.T) {
+	l, recent := newRingLogger(t)
+
+	l.PrintLogger("lib", "warn").Printf("x %d\n", 1)
+	l.SaramaLogger().Println("connected")
+	gorm := l.GormLogger()
+	gorm.Print("sql", "db.go:1", 2*time.Millisecond, "SELECT ?", []interface{}{1}, int64(3))
+	gorm.Print("error", "db.go:2", errors.New("bad"))
+
+	restore, err := l.RedirectStdLog("warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdlog.Print("std")
+	restore()
+
+	entries := recent.Entries()
+	if len(entries) != 5 {
+		t.Fatalf("entries %+v", entries)
+	}
+	for i, expected := range []struct {
+		level   zapcore.Level
+		logger  string
+		message string
+	}{
+		{zapcore.WarnLevel, "lib", "x 1"},
+		{zapcore.InfoLevel, "sarama", "connected"},
+		{zapcore.DebugLevel, "gorm", "sql"},
+		{zapcore.ErrorLevel, "gorm", "bad"},
+		{zapcore.WarnLevel, "stdlog", "std"},
+	} {
+		e := entries[i]
+		if e.Level != expected.level || e.Logger != expected.logger || e.Message != expected.message {
+			t.Errorf("entry %d %+v", i, e)
+		}
+	}
+
+	sql := entries[2]
+	for key, value := range map[string]string{
+		"sql": "SELECT ?", "vars": "[1]", "duration": "2ms", "rows": "3", "source": "db.go:1",
+	} {
+		if v, _ := sql.Field(key); v != value {
+			t.Errorf("sql %s=%s", key, v)
+		}
+	}
+}
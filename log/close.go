@@ -0,0 +1,62 @@
+package log
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FatalCloseTimeout bounds the Close run before a Fatal entry exits.
+var FatalCloseTimeout = 5 * time.Second
+
+// Close stops the hooks accepting entries, drains their queues, flushes
+// the kafka producer and pending webhook requests, then closes the files.
+// It returns once done or ctx is done, with a summary of what is left.
+func (log *Logger) Close(ctx context.Context) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	log.Sync()
+	if log.cores == nil {
+		return nil
+	}
+	return log.cores.shutdown(ctx)
+}
+
+// Close closes the standard logger, see Logger.Close.
+func Close(ctx context.Context) error {
+	return logger.Close(ctx)
+}
+
+// fatalCore closes its logger when a Fatal entry is written. It is added
+// after the other cores so the entry is queued by the hooks before the
+// process exits.
+type fatalCore struct {
+	log *Logger
+}
+
+func (c *fatalCore) Enabled(lvl zapcore.Level) bool {
+	return lvl == zapcore.FatalLevel
+}
+
+func (c *fatalCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *fatalCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *fatalCore) Write(zapcore.Entry, []zapcore.Field) error {
+	ctx, cancel := context.WithTimeout(context.Background(), FatalCloseTimeout)
+	defer cancel()
+	return c.log.Close(ctx)
+}
+
+func (c *fatalCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+)
+
+func TestClose(t *testing.T) {
+	var (
+		received int32
+		block    = make(chan struct{})
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "block") {
+			select {
+			case <-block:
+			case <-r.Context().Done():
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&received, 1)
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	webhook := func(path string) *hook.WebHookConfig {
+		return &hook.WebHookConfig{
+			CoreConfig:  hook.CoreConfig{QueueLength: 100, Level: "error"},
+			Host:        srv.URL + path,
+			Method:      hook.MethodPOST,
+			Message:     "{{content}}",
+			KVMessage:   "{{key}}={{value}};",
+			ContentType: "text/plain",
+		}
+	}
+
+	logger, err := New(&Config{DisableStdout: true, WebHook: []*hook.WebHookConfig{webhook("/")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		logger.Error("queued")
+	}
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&received); n != 20 {
+		t.Fatalf("expected 20 requests flushed, got %d", n)
+	}
+	logger.Error("closed")
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt32(&received); n != 20 {
+		t.Fatalf("expected entries dropped after close, got %d", n)
+	}
+
+	// the deadline is reached on a blocked webhook
+	logger, err = New(&Config{DisableStdout: true, WebHook: []*hook.WebHookConfig{webhook("/block")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Error("blocked")
+	logger.Error("left")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = logger.Close(ctx)
+	if err == nil || !strings.Contains(err.Error(), "webhook.0: context deadline exceeded") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
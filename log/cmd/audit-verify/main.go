@@ -0,0 +1,35 @@
+// Command audit-verify verifies the audit files written by log.Audit as a
+// single chain, reporting any gap or change:
+//
+//	audit-verify logs/audit.log*
+//
+// The files are ordered by their first seq, the ones ending with .gz or .zst
+// are decompressed. It exits with 1 if a problem is found.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hkjojo/go-toolkits/log"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: audit-verify file...")
+		os.Exit(2)
+	}
+
+	report, err := log.VerifyAuditFiles(os.Args[1:]...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, p := range report.Problems {
+		fmt.Println(p)
+	}
+	fmt.Printf("%d records, seq %d to %d, head %s\n", report.Records, report.First, report.Last, report.Head)
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
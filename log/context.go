@@ -0,0 +1,190 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	mmetadata "github.com/micro/go-micro/v2/metadata"
+	opentracing "github.com/opentracing/opentracing-go"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+)
+
+// Context field keys
+const (
+	TraceIDKey   = "trace_id"
+	SpanIDKey    = "span_id"
+	ServiceKey   = "service"
+	RequestIDKey = "request_id"
+)
+
+// ContextExtractor returns key-value pairs to attach to entries logged
+// with a context, as they are in With.
+type ContextExtractor func(ctx context.Context) []interface{}
+
+var (
+	extractorsMu sync.RWMutex
+	extractors   = []ContextExtractor{TraceExtractor, MetadataExtractor}
+)
+
+type loggerKey struct{}
+
+// RegisterContextExtractor adds an extractor applied by Ctx, e.g. for a
+// value set by a middleware:
+//
+//	log.RegisterContextExtractor(func(ctx context.Context) []interface{} {
+//		if v, ok := ctx.Value(tenantKey{}).(string); ok {
+//			return []interface{}{"tenant", v}
+//		}
+//		return nil
+//	})
+func RegisterContextExtractor(e ContextExtractor) {
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+	extractors = append(extractors, e)
+}
+
+// TraceExtractor extracts the trace and span ids of the opentracing span in
+// ctx. Ids are read from the jaeger span context format
+// "trace:span:parent:flags".
+func TraceExtractor(ctx context.Context) []interface{} {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+
+	sc, ok := span.Context().(fmt.Stringer)
+	if !ok {
+		return nil
+	}
+	ids := strings.Split(sc.String(), ":")
+	if len(ids) < 2 || ids[0] == "" {
+		return nil
+	}
+	return []interface{}{TraceIDKey, ids[0], SpanIDKey, ids[1]}
+}
+
+// MetadataExtractor extracts the service set by cli.WithServiceContext or
+// microtools.WithServiceContext and the x-request-id, from the grpc
+// metadata, incoming first then outgoing, then from the go-micro one.
+func MetadataExtractor(ctx context.Context) []interface{} {
+	var kvs []interface{}
+	if v := metadataValue(ctx, "service"); v != "" {
+		kvs = append(kvs, ServiceKey, v)
+	}
+	if v := metadataValue(ctx, "x-request-id"); v != "" {
+		kvs = append(kvs, RequestIDKey, v)
+	}
+	return kvs
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if vs := md.Get(key); len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if vs := md.Get(key); len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	if v, ok := mmetadata.Get(ctx, key); ok {
+		return v
+	}
+	return ""
+}
+
+func contextFields(ctx context.Context) []interface{} {
+	extractorsMu.RLock()
+	defer extractorsMu.RUnlock()
+
+	var kvs []interface{}
+	for _, e := range extractors {
+		kvs = append(kvs, e(ctx)...)
+	}
+	return kvs
+}
+
+// NewContext returns a copy of ctx carrying s, which is used by Ctx
+// instead of the standard logger.
+func NewContext(ctx context.Context, s *SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, s)
+}
+
+// FromContext returns the logger stashed by NewContext, or the standard
+// logger if there is none.
+func FromContext(ctx context.Context) *SugaredLogger {
+	if ctx != nil {
+		if s, ok := ctx.Value(loggerKey{}).(*SugaredLogger); ok {
+			return s
+		}
+	}
+	return sugger
+}
+
+// ContextWith stashes the logger of ctx with the key-value pairs attached,
+// as they are in With.
+func ContextWith(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	return NewContext(ctx, &SugaredLogger{FromContext(ctx).With(keysAndValues...)})
+}
+
+// Ctx returns the logger of ctx with the fields of the registered context
+// extractors attached.
+func Ctx(ctx context.Context) *SugaredLogger {
+	s := FromContext(ctx)
+	if ctx == nil {
+		return s
+	}
+
+	kvs := contextFields(ctx)
+	if len(kvs) == 0 {
+		return s
+	}
+	return &SugaredLogger{s.With(kvs...)}
+}
+
+// ctxHelper is Ctx skipping the frame of the package helpers.
+func ctxHelper(ctx context.Context) *SugaredLogger {
+	s := FromContext(ctx)
+	if s == sugger {
+		s = helper
+	} else {
+		s = &SugaredLogger{s.Desugar().WithOptions(zap.AddCallerSkip(1)).Sugar()}
+	}
+	if ctx == nil {
+		return s
+	}
+
+	kvs := contextFields(ctx)
+	if len(kvs) == 0 {
+		return s
+	}
+	return &SugaredLogger{s.With(kvs...)}
+}
+
+// DebugCtx logs a message with the context fields and some additional
+// context. The variadic key-value pairs are treated as they are in With.
+func DebugCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxHelper(ctx).Debugw(msg, keysAndValues...)
+}
+
+// InfoCtx logs a message with the context fields and some additional
+// context. The variadic key-value pairs are treated as they are in With.
+func InfoCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxHelper(ctx).Infow(msg, keysAndValues...)
+}
+
+// WarnCtx logs a message with the context fields and some additional
+// context. The variadic key-value pairs are treated as they are in With.
+func WarnCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxHelper(ctx).Warnw(msg, keysAndValues...)
+}
+
+// ErrorCtx logs a message with the context fields and some additional
+// context. The variadic key-value pairs are treated as they are in With.
+func ErrorCtx(ctx context.Context, msg string, keysAndValues ...interface{}) {
+	ctxHelper(ctx).Errorw(msg, keysAndValues...)
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	mmetadata "github.com/micro/go-micro/v2/metadata"
+	opentracing "github.com/opentracing/opentracing-go"
+	"google.golang.org/grpc/metadata"
+)
+
+type testSpanContext struct{}
+
+func (testSpanContext) ForeachBaggageItem(func(k, v string) bool) {}
+func (testSpanContext) String() string                            { return "4bf92f3577b34da6:a3ce929d0e0e4736:0:1" }
+
+type testSpan struct {
+	opentracing.Span
+}
+
+func (testSpan) Context() opentracing.SpanContext { return testSpanContext{} }
+
+func TestCtx(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	logger, err := New(&Config{
+		Path:          path,
+		MaxSize:       1,
+		Format:        "json",
+		DisableStdout: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type tenantKey struct{}
+	extractorsMu.RLock()
+	registered := extractors
+	extractorsMu.RUnlock()
+	defer func() {
+		extractorsMu.Lock()
+		extractors = registered
+		extractorsMu.Unlock()
+	}()
+	RegisterContextExtractor(func(ctx context.Context) []interface{} {
+		if v, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []interface{}{"tenant", v}
+		}
+		return nil
+	})
+
+	ctx := NewContext(context.Background(), logger.Sugar())
+	ctx = ContextWith(ctx, "order", 1)
+	ctx = opentracing.ContextWithSpan(ctx, testSpan{})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("service", "user", "x-request-id", "r-1"))
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	InfoCtx(ctx, "hello", "key", "value")
+	Ctx(context.Background()).Info("standard")
+	if FromContext(context.Background()) != sugger {
+		t.Fatal("expected standard logger without stash")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{
+		`"trace_id":"4bf92f3577b34da6"`,
+		`"span_id":"a3ce929d0e0e4736"`,
+		`"service":"user"`,
+		`"request_id":"r-1"`,
+		`"tenant":"acme"`,
+		`"key":"value"`,
+		`"order":1`,
+	} {
+		if !strings.Contains(string(data), field) {
+			t.Fatalf("expected %s in %s", field, data)
+		}
+	}
+	if strings.Contains(string(data), "standard") {
+		t.Fatalf("unexpected entry in %s", data)
+	}
+}
+
+func TestMetadataExtractor(t *testing.T) {
+	// as set by microtools.WithServiceContext
+	ctx := mmetadata.Set(context.Background(), "service", "orders")
+	kvs := MetadataExtractor(ctx)
+	if len(kvs) != 2 || kvs[0] != ServiceKey || kvs[1] != "orders" {
+		t.Fatalf("unexpected go-micro metadata fields %v", kvs)
+	}
+
+	// the grpc metadata comes first
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("service", "user"))
+	if kvs = MetadataExtractor(ctx); len(kvs) != 2 || kvs[1] != "user" {
+		t.Fatalf("unexpected grpc metadata fields %v", kvs)
+	}
+}
@@ -0,0 +1,92 @@
+// Package encoder implements the zapcore encoders of the formats not
+// provided by zap.
+package encoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Formats
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+	FormatText    = "text" // same as console
+	FormatFix     = "fix"
+	FormatLogfmt  = "logfmt"
+	FormatGELF    = "gelf"
+)
+
+// New returns the encoder of format, console if empty.
+func New(format string, cfg zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(cfg), nil
+	case "", FormatConsole, FormatText:
+		return zapcore.NewConsoleEncoder(cfg), nil
+	case FormatFix:
+		return NewFixEncoder(cfg), nil
+	case FormatLogfmt:
+		return NewLogfmtEncoder(cfg), nil
+	case FormatGELF:
+		return NewGELFEncoder(cfg), nil
+	}
+	return nil, fmt.Errorf("encoder: unknown format %q", format)
+}
+
+// marshalValue returns the json of an array, object or reflected value.
+func marshalValue(add func(enc zapcore.ObjectEncoder) error) (string, error) {
+	enc := zapcore.NewMapObjectEncoder()
+	if err := add(enc); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(enc.Fields[""])
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// appendJSONString writes s quoted and escaped as a json string.
+func appendJSONString(buf *buffer.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.AppendByte('"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf.AppendByte('\\')
+				buf.AppendByte(c)
+			case c == '\n':
+				buf.AppendString(`\n`)
+			case c == '\r':
+				buf.AppendString(`\r`)
+			case c == '\t':
+				buf.AppendString(`\t`)
+			case c < 0x20:
+				buf.AppendString(`\u00`)
+				buf.AppendByte(hex[c>>4])
+				buf.AppendByte(hex[c&0xF])
+			default:
+				buf.AppendByte(c)
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.AppendString("\ufffd")
+		} else {
+			buf.AppendString(s[i : i+size])
+		}
+		i += size
+	}
+	buf.AppendByte('"')
+}
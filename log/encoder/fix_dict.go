@@ -0,0 +1,133 @@
+package encoder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FixSOH delimits the fields of a FIX message.
+const FixSOH = '\x01'
+
+// FixDictionary names the tags of a FIX version.
+type FixDictionary map[int]string
+
+// Fix42 is the dictionary of the FIX 4.2 tags commonly logged.
+var Fix42 = FixDictionary{
+	1: "Account", 6: "AvgPx", 7: "BeginSeqNo", 8: "BeginString", 9: "BodyLength",
+	10: "CheckSum", 11: "ClOrdID", 12: "Commission", 13: "CommType", 14: "CumQty",
+	15: "Currency", 16: "EndSeqNo", 17: "ExecID", 18: "ExecInst", 19: "ExecRefID",
+	20: "ExecTransType", 21: "HandlInst", 22: "IDSource", 30: "LastMkt", 31: "LastPx",
+	32: "LastShares", 34: "MsgSeqNum", 35: "MsgType", 36: "NewSeqNo", 37: "OrderID",
+	38: "OrderQty", 39: "OrdStatus", 40: "OrdType", 41: "OrigClOrdID", 43: "PossDupFlag",
+	44: "Price", 45: "RefSeqNum", 47: "Rule80A", 48: "SecurityID", 49: "SenderCompID",
+	50: "SenderSubID", 52: "SendingTime", 54: "Side", 55: "Symbol", 56: "TargetCompID",
+	57: "TargetSubID", 58: "Text", 59: "TimeInForce", 60: "TransactTime", 62: "ValidUntilTime",
+	63: "SettlmntTyp", 64: "FutSettDate", 65: "SymbolSfx", 66: "ListID", 75: "TradeDate",
+	76: "ExecBroker", 97: "PossResend", 98: "EncryptMethod", 99: "StopPx", 100: "ExDestination",
+	102: "CxlRejReason", 103: "OrdRejReason", 108: "HeartBtInt", 109: "ClientID",
+	110: "MinQty", 111: "MaxFloor", 112: "TestReqID", 115: "OnBehalfOfCompID",
+	117: "QuoteID", 122: "OrigSendingTime", 123: "GapFillFlag", 126: "ExpireTime",
+	128: "DeliverToCompID", 131: "QuoteReqID", 132: "BidPx", 133: "OfferPx",
+	134: "BidSize", 135: "OfferSize", 141: "ResetSeqNumFlag", 146: "NoRelatedSym",
+	150: "ExecType", 151: "LeavesQty", 152: "CashOrderQty", 167: "SecurityType",
+	200: "MaturityMonthYear", 201: "PutOrCall", 202: "StrikePrice", 205: "MaturityDay",
+	207: "SecurityExchange", 262: "MDReqID", 263: "SubscriptionRequestType",
+	264: "MarketDepth", 265: "MDUpdateType", 267: "NoMDEntryTypes", 268: "NoMDEntries",
+	269: "MDEntryType", 270: "MDEntryPx", 271: "MDEntrySize", 272: "MDEntryDate",
+	273: "MDEntryTime", 279: "MDUpdateAction", 281: "MDReqRejReason", 320: "SecurityReqID",
+	336: "TradingSessionID", 371: "RefTagID", 372: "RefMsgType", 373: "SessionRejectReason",
+	379: "BusinessRejectRefID", 380: "BusinessRejectReason", 383: "MaxMessageSize",
+	434: "CxlRejResponseTo", 553: "Username", 554: "Password",
+}
+
+// Fix44 is the dictionary of the FIX 4.4 tags commonly logged.
+var Fix44 = FixDictionary{
+	22: "SecurityIDSource", 32: "LastQty", 63: "SettlType", 64: "SettlDate",
+	447: "PartyIDSource", 448: "PartyID", 452: "PartyRole", 453: "NoPartyIDs",
+	460: "Product", 461: "CFICode", 541: "MaturityDate", 555: "NoLegs",
+	600: "LegSymbol", 624: "LegSide", 687: "LegQty", 789: "NextExpectedMsgSeqNum",
+	1128: "ApplVerID", 1137: "DefaultApplVerID",
+}
+
+func init() {
+	// 4.4 renames a few 4.2 tags and deprecates 20, 47 and 76
+	for tag, name := range Fix42 {
+		switch tag {
+		case 20, 47, 76:
+			continue
+		}
+		if _, ok := Fix44[tag]; !ok {
+			Fix44[tag] = name
+		}
+	}
+}
+
+// FixDictionaryFor returns the dictionary of a BeginString, Fix44 for the
+// versions unknown.
+func FixDictionaryFor(beginString string) FixDictionary {
+	switch beginString {
+	case "FIX.4.0", "FIX.4.1", "FIX.4.2":
+		return Fix42
+	}
+	return Fix44
+}
+
+func isFixMessage(s string) bool {
+	return strings.HasPrefix(s, "8=FIX") && strings.IndexByte(s, FixSOH) > 0
+}
+
+// DecodeFix writes the fields of a SOH delimited FIX message as
+// tag(name)=value separated by |, s is returned as is if not a FIX message.
+func DecodeFix(s string) string {
+	if !isFixMessage(s) {
+		return s
+	}
+
+	begin := s[2:strings.IndexByte(s, FixSOH)]
+	dict := FixDictionaryFor(begin)
+
+	var b strings.Builder
+	for _, field := range strings.Split(strings.TrimSuffix(s, string(FixSOH)), string(FixSOH)) {
+		if b.Len() > 0 {
+			b.WriteByte('|')
+		}
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			b.WriteString(field)
+			continue
+		}
+		b.WriteString(field[:i])
+		if tag, err := strconv.Atoi(field[:i]); err == nil {
+			if name, ok := dict[tag]; ok {
+				b.WriteString("(" + name + ")")
+			}
+		}
+		b.WriteString(field[i:])
+	}
+	return b.String()
+}
+
+// EncodeFix reverts DecodeFix, returning the SOH delimited FIX message.
+func EncodeFix(s string) string {
+	if !strings.HasPrefix(s, "8=FIX") && !strings.HasPrefix(s, "8(") {
+		return s
+	}
+
+	var b strings.Builder
+	for _, field := range strings.Split(s, "|") {
+		i := strings.IndexByte(field, '=')
+		if i < 0 {
+			b.WriteString(field)
+			b.WriteByte(FixSOH)
+			continue
+		}
+		tag := field[:i]
+		if j := strings.IndexByte(tag, '('); j > 0 && strings.HasSuffix(tag, ")") {
+			tag = tag[:j]
+		}
+		b.WriteString(tag)
+		b.WriteString(field[i:])
+		b.WriteByte(FixSOH)
+	}
+	return b.String()
+}
@@ -25,25 +25,54 @@ func getFixEncoder() *fixEncoder {
 func putFixEncoder(enc *fixEncoder) {
 	enc.EncoderConfig = nil
 	enc.buf = nil
+	enc.prefix = ""
 	_fixPool.Put(enc)
 }
 
+// fixEncoder writes the time, the message then each field as key=value,
+// separated by tabs. Tabs, newlines and backslashes of the values are
+// escaped so FixParser reads the lines back. Nested keys are joined by dots
+// and array elements by commas.
 type fixEncoder struct {
 	*zapcore.EncoderConfig
 	buf        *buffer.Buffer
 	reflectBuf *buffer.Buffer
+	decode     bool   // decode FIX messages
+	prefix     string // of nested keys
+	depth      int    // of arrays
+	sep        bool   // an array element is written
+}
+
+// FixOption configures the fix encoder.
+type FixOption func(*fixEncoder)
+
+// WithFixDecode writes the FIX messages of string values as
+// tag(name)=value separated by |, see DecodeFix.
+func WithFixDecode() FixOption {
+	return func(enc *fixEncoder) {
+		enc.decode = true
+	}
 }
 
 // NewFixEncoder ...
-func NewFixEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
-	return &fixEncoder{
+func NewFixEncoder(cfg zapcore.EncoderConfig, opts ...FixOption) zapcore.Encoder {
+	enc := &fixEncoder{
 		EncoderConfig: &cfg,
+		buf:           _bufferPool.Get(),
+	}
+	for _, o := range opts {
+		o(enc)
 	}
+	return enc
 }
 
 func (enc *fixEncoder) clone() *fixEncoder {
 	clone := getFixEncoder()
 	clone.EncoderConfig = enc.EncoderConfig
+	clone.decode = enc.decode
+	clone.prefix = enc.prefix
+	clone.depth = 0
+	clone.sep = false
 	clone.buf = _bufferPool.Get()
 	return clone
 }
@@ -66,6 +95,10 @@ func (enc *fixEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*
 		final.AppendString(ent.Message)
 	}
 
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.AddString(final.NameKey, ent.LoggerName)
+	}
+	final.buf.Write(enc.buf.Bytes())
 	for i := range fields {
 		fields[i].AddTo(final)
 	}
@@ -77,17 +110,22 @@ func (enc *fixEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*
 
 // AddArray implements ObjectEncoder.
 func (enc *fixEncoder) AddArray(key string, v zapcore.ArrayMarshaler) error {
-	enc.buf.AppendString(key)
-	enc.buf.AppendByte('[')
-	err := v.MarshalLogArray(enc)
-	enc.buf.AppendByte(']')
-	return err
+	enc.addKey(key)
+	return enc.appendArray(v)
 }
 
 // AddObject implements ObjectEncoder.
 func (enc *fixEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
-	enc.buf.AppendString(k)
-	return enc.AppendObject(v)
+	if enc.depth > 0 {
+		enc.addKey(k)
+		return enc.AppendObject(v)
+	}
+
+	prefix := enc.prefix
+	enc.prefix += k + "."
+	err := v.MarshalLogObject(enc)
+	enc.prefix = prefix
+	return err
 }
 
 // AddBinary implements ObjectEncoder.
@@ -95,158 +133,225 @@ func (enc *fixEncoder) AddBinary(k string, v []byte) {
 	enc.AddString(k, base64.StdEncoding.EncodeToString(v))
 }
 
+// addKey starts a field, or an object key within an array.
+func (enc *fixEncoder) addKey(k string) {
+	if enc.depth > 0 {
+		enc.appendSep()
+		enc.escape(k, true)
+		enc.buf.AppendByte(':')
+		enc.sep = false
+		return
+	}
+	enc.buf.AppendByte('\t')
+	enc.escape(enc.prefix+k, true)
+	enc.buf.AppendByte('=')
+}
+
+// appendSep separates the elements of an array.
+func (enc *fixEncoder) appendSep() {
+	if enc.depth == 0 {
+		return
+	}
+	if enc.sep {
+		enc.buf.AppendByte(',')
+	}
+	enc.sep = true
+}
+
+func (enc *fixEncoder) appendArray(arr zapcore.ArrayMarshaler) error {
+	sep := enc.sep
+	enc.depth++
+	enc.sep = false
+	enc.buf.AppendByte('[')
+	err := arr.MarshalLogArray(enc)
+	enc.buf.AppendByte(']')
+	enc.depth--
+	enc.sep = sep
+	return err
+}
+
+// escape writes s with tabs, newlines and backslashes escaped, and = too
+// in keys.
+func (enc *fixEncoder) escape(s string, key bool) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			enc.buf.AppendString(`\\`)
+		case '\t':
+			enc.buf.AppendString(`\t`)
+		case '\n':
+			enc.buf.AppendString(`\n`)
+		case '\r':
+			enc.buf.AppendString(`\r`)
+		case '=':
+			if key {
+				enc.buf.AppendString(`\=`)
+				continue
+			}
+			enc.buf.AppendByte(c)
+		default:
+			enc.buf.AppendByte(c)
+		}
+	}
+}
+
 // AddByteString implements ObjectEncoder.
 func (enc *fixEncoder) AddByteString(k string, v []byte) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendByteString(v)
 }
 
 // AddBool implements ObjectEncoder.
 func (enc *fixEncoder) AddBool(k string, v bool) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendBool(v)
 }
 
 // AddDuration implements ObjectEncoder.
 func (enc *fixEncoder) AddDuration(k string, v time.Duration) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendDuration(v)
 }
 
 // AddComplex128 implements ObjectEncoder.
 func (enc *fixEncoder) AddComplex128(k string, v complex128) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendComplex128(v)
 }
 
 // AddComplex64 implements ObjectEncoder.
 func (enc *fixEncoder) AddComplex64(k string, v complex64) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendComplex64(v)
 }
 
 // AddFloat64 implements ObjectEncoder.
 func (enc *fixEncoder) AddFloat64(k string, v float64) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendFloat64(v)
 }
 
 // AddFloat32 implements ObjectEncoder.
 func (enc *fixEncoder) AddFloat32(k string, v float32) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendFloat32(v)
 }
 
 // AddInt implements ObjectEncoder.
 func (enc *fixEncoder) AddInt(k string, v int) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendInt(v)
 }
 
 // AddInt64 implements ObjectEncoder.
 func (enc *fixEncoder) AddInt64(k string, v int64) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendInt64(v)
 }
 
 // AddInt32 implements ObjectEncoder.
 func (enc *fixEncoder) AddInt32(k string, v int32) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendInt32(v)
 }
 
 // AddInt16 implements ObjectEncoder.
 func (enc *fixEncoder) AddInt16(k string, v int16) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendInt16(v)
 }
 
 // AddInt8 implements ObjectEncoder.
 func (enc *fixEncoder) AddInt8(k string, v int8) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendInt8(v)
 }
 
 // AddString implements ObjectEncoder.
 func (enc *fixEncoder) AddString(k string, v string) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendString(v)
 }
 
 // AddTime implements ObjectEncoder.
-func (enc fixEncoder) AddTime(k string, v time.Time) {
-	enc.buf.AppendString(k)
+func (enc *fixEncoder) AddTime(k string, v time.Time) {
+	enc.addKey(k)
 	enc.AppendTime(v)
 }
 
 // AddUint implements ObjectEncoder.
 func (enc *fixEncoder) AddUint(k string, v uint) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUint(v)
 }
 
 // AddUint64 implements ObjectEncoder.
 func (enc *fixEncoder) AddUint64(k string, v uint64) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUint64(v)
 }
 
 // AddUint32 implements ObjectEncoder.
 func (enc *fixEncoder) AddUint32(k string, v uint32) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUint32(v)
 }
 
 // AddUint16 implements ObjectEncoder.
 func (enc *fixEncoder) AddUint16(k string, v uint16) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUint16(v)
 }
 
 // AddUint8 implements ObjectEncoder.
 func (enc *fixEncoder) AddUint8(k string, v uint8) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUint8(v)
 }
 
 // AddUintptr implements ObjectEncoder.
 func (enc *fixEncoder) AddUintptr(k string, v uintptr) {
-	enc.buf.AppendString(k)
+	enc.addKey(k)
 	enc.AppendUintptr(v)
 }
 
 // AddReflected implements ObjectEncoder.
 func (enc *fixEncoder) AddReflected(k string, v interface{}) error {
-	enc.buf.AppendString(k)
-	enc.AppendString(fmt.Sprintf("%v", v))
-	return nil
+	enc.addKey(k)
+	return enc.AppendReflected(v)
 }
 
 // OpenNamespace implements ObjectEncoder.
 func (enc *fixEncoder) OpenNamespace(k string) {
-	enc.buf.AppendString(k)
+	enc.prefix += k + "."
 }
 
 func (enc *fixEncoder) AppendByteString(val []byte) {
-	enc.buf.Write(val)
+	enc.AppendString(string(val))
 }
 
 func (enc *fixEncoder) AppendString(val string) {
-	enc.buf.AppendString(val)
+	enc.appendSep()
+	if enc.decode && isFixMessage(val) {
+		val = DecodeFix(val)
+	}
+	enc.escape(val, false)
 }
 
 func (enc *fixEncoder) AppendArray(arr zapcore.ArrayMarshaler) error {
-	enc.buf.AppendByte('[')
-	err := arr.MarshalLogArray(enc)
-	enc.buf.AppendByte(']')
-	return err
+	enc.appendSep()
+	return enc.appendArray(arr)
 }
 
 func (enc *fixEncoder) AppendBool(val bool) {
+	enc.appendSep()
 	enc.buf.AppendBool(val)
 }
 
 func (enc *fixEncoder) AppendDuration(val time.Duration) {
+	enc.appendSep()
+	depth := enc.depth
+	enc.depth = 0
 	cur := enc.buf.Len()
 	enc.EncodeDuration(val, enc)
 	if cur == enc.buf.Len() {
@@ -254,9 +359,13 @@ func (enc *fixEncoder) AppendDuration(val time.Duration) {
 		// JSON valid.
 		enc.AppendInt64(int64(val))
 	}
+	enc.depth = depth
 }
 
 func (enc *fixEncoder) AppendTime(val time.Time) {
+	enc.appendSep()
+	depth := enc.depth
+	enc.depth = 0
 	cur := enc.buf.Len()
 	enc.EncodeTime(val, enc)
 	if cur == enc.buf.Len() {
@@ -264,10 +373,19 @@ func (enc *fixEncoder) AppendTime(val time.Time) {
 		// output JSON valid.
 		enc.AppendInt64(val.UnixNano())
 	}
+	enc.depth = depth
 }
 
 func (enc *fixEncoder) AppendObject(v zapcore.ObjectMarshaler) error {
+	enc.appendSep()
+	sep := enc.sep
+	enc.depth++
+	enc.sep = false
+	enc.buf.AppendByte('{')
 	err := v.MarshalLogObject(enc)
+	enc.buf.AppendByte('}')
+	enc.depth--
+	enc.sep = sep
 	return err
 }
 
@@ -290,6 +408,7 @@ func (enc *fixEncoder) AppendUint8(v uint8)         { enc.AppendUint64(uint64(v)
 func (enc *fixEncoder) AppendUintptr(v uintptr)     { enc.AppendUint64(uint64(v)) }
 
 func (enc *fixEncoder) appendFloat(val float64, bitSize int) {
+	enc.appendSep()
 	switch {
 	case math.IsNaN(val):
 		enc.buf.AppendString(`NaN`)
@@ -303,14 +422,17 @@ func (enc *fixEncoder) appendFloat(val float64, bitSize int) {
 }
 
 func (enc *fixEncoder) AppendInt64(val int64) {
+	enc.appendSep()
 	enc.buf.AppendInt(val)
 }
 
 func (enc *fixEncoder) AppendUint64(val uint64) {
+	enc.appendSep()
 	enc.buf.AppendUint(val)
 }
 
 func (enc *fixEncoder) AppendComplex128(val complex128) {
+	enc.appendSep()
 	// Cast to a platform-independent, fixed-size type.
 	r, i := float64(real(val)), float64(imag(val))
 	enc.buf.AppendByte('"')
@@ -0,0 +1,186 @@
+package encoder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fixTimeLayouts are tried in order to parse the time of a line.
+var fixTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000Z0700", // zapcore.ISO8601TimeEncoder
+	"2006-01-02 15:04:05.000",
+	"2006-01-02 15:04:05",
+}
+
+// FixField is a field of a FixRecord, nested keys are joined by dots.
+type FixField struct {
+	Key   string
+	Value string
+}
+
+// FixRecord is a line written by the fix encoder.
+type FixRecord struct {
+	Time    time.Time
+	Message string
+	Logger  string
+	Fields  []FixField
+}
+
+// Field returns the value of key.
+func (r *FixRecord) Field(key string) (string, bool) {
+	for _, f := range r.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// FixParser reads back the lines of a fix encoder with the same config.
+type FixParser struct {
+	time    bool
+	message bool
+	name    string
+	decode  bool
+}
+
+// NewFixParser returns a parser of the lines of NewFixEncoder(cfg, opts...),
+// the FIX messages decoded by WithFixDecode are encoded back.
+func NewFixParser(cfg zapcore.EncoderConfig, opts ...FixOption) *FixParser {
+	enc := &fixEncoder{}
+	for _, o := range opts {
+		o(enc)
+	}
+	return &FixParser{
+		time:    cfg.TimeKey != "",
+		message: cfg.MessageKey != "",
+		name:    cfg.NameKey,
+		decode:  enc.decode,
+	}
+}
+
+// Parse parses a line, with or without its line ending.
+func (p *FixParser) Parse(line string) (*FixRecord, error) {
+	line = strings.TrimRight(line, "\r\n")
+	cols := strings.Split(line, "\t")
+	record := &FixRecord{}
+
+	if p.time {
+		t, err := parseFixTime(cols[0])
+		if err != nil {
+			return nil, err
+		}
+		record.Time = t
+		cols = cols[1:]
+	}
+
+	if p.message {
+		if len(cols) == 0 {
+			return nil, fmt.Errorf("fix: no message in %q", line)
+		}
+		record.Message = p.value(unescapeFix(cols[0]))
+		cols = cols[1:]
+	}
+
+	for j, col := range cols {
+		i := fixKeyEnd(col)
+		if i < 0 {
+			return nil, fmt.Errorf("fix: bad field %q", col)
+		}
+		if j == 0 && p.name != "" && unescapeFix(col[:i]) == p.name {
+			record.Logger = unescapeFix(col[i+1:])
+			continue
+		}
+		record.Fields = append(record.Fields, FixField{
+			Key:   unescapeFix(col[:i]),
+			Value: p.value(unescapeFix(col[i+1:])),
+		})
+	}
+	return record, nil
+}
+
+// Scan parses the lines of r, calling fn for each record until it fails.
+func (p *FixParser) Scan(r io.Reader, fn func(*FixRecord) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record, err := p.Parse(scanner.Text())
+		if err != nil {
+			return err
+		}
+		if err = fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *FixParser) value(s string) string {
+	if p.decode && strings.HasPrefix(s, "8(") {
+		return EncodeFix(s)
+	}
+	return s
+}
+
+func parseFixTime(s string) (time.Time, error) {
+	for _, layout := range fixTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	// nanos since epoch if the time encoder is a no-op
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(0, n), nil
+	}
+	return time.Time{}, fmt.Errorf("fix: bad time %q", s)
+}
+
+// fixKeyEnd returns the index of the first = not escaped.
+func fixKeyEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeFix(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
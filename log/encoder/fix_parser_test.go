@@ -0,0 +1,224 @@
+package encoder
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var fixTestConfig = zapcore.EncoderConfig{
+	MessageKey:     "M",
+	LevelKey:       "L",
+	TimeKey:        "T",
+	NameKey:        "N",
+	CallerKey:      "C",
+	StacktraceKey:  "S",
+	EncodeLevel:    zapcore.LowercaseLevelEncoder,
+	EncodeTime:     zapcore.ISO8601TimeEncoder,
+	EncodeDuration: zapcore.SecondsDurationEncoder,
+	EncodeCaller:   zapcore.ShortCallerEncoder,
+}
+
+type fixUser struct {
+	Name  string
+	Roles []string
+}
+
+func (u fixUser) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	return enc.AddArray("roles", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, r := range u.Roles {
+			arr.AppendString(r)
+		}
+		return nil
+	}))
+}
+
+func TestFixParse(t *testing.T) {
+	type bar struct {
+		Key string  `json:"key"`
+		Val float64 `json:"val"`
+	}
+
+	type foo struct {
+		A string  `json:"aee"`
+		B int     `json:"bee"`
+		C float64 `json:"cee"`
+		D []bar   `json:"dee"`
+	}
+
+	const order = "8=FIX.4.4\x019=65\x0135=D\x0149=CLIENT\x0156=BROKER\x0111=ord-1\x0154=1\x0138=100\x0144=1.25\x0110=123\x01"
+
+	tests := []struct {
+		desc     string
+		opts     []FixOption
+		ent      zapcore.Entry
+		fields   []zapcore.Field
+		expected []FixField
+	}{
+		{
+			desc: "info entry with some fields",
+			ent: zapcore.Entry{
+				Level:      zapcore.InfoLevel,
+				Time:       time.Date(2018, 6, 19, 16, 33, 42, 99, time.UTC),
+				LoggerName: "bob",
+				Message:    "lob law",
+			},
+			fields: []zapcore.Field{
+				zap.String("so", "passes"),
+				zap.Int("answer", 42),
+				zap.Float64("common_pie", 3.14),
+				zap.Reflect("null_value", nil),
+				zap.Reflect("such", foo{
+					A: "lol",
+					B: 123,
+					C: 0.9999,
+					D: []bar{
+						{"pi", 3.141592653589793},
+						{"tau", 6.283185307179586},
+					},
+				}),
+			},
+			expected: []FixField{
+				{"so", "passes"},
+				{"answer", "42"},
+				{"common_pie", "3.14"},
+				{"null_value", "<nil>"},
+				{"such", "{lol 123 0.9999 [{pi 3.141592653589793} {tau 6.283185307179586}]}"},
+			},
+		},
+		{
+			desc: "escapes, objects and arrays",
+			ent: zapcore.Entry{
+				Level:   zapcore.WarnLevel,
+				Time:    time.Date(2018, 6, 19, 16, 33, 42, 0, time.UTC),
+				Message: "multi\tline\nmessage",
+			},
+			fields: []zapcore.Field{
+				zap.String("path", `c:\tmp`),
+				zap.String("a=b", "x=y"),
+				zap.Object("user", fixUser{"bob", []string{"admin", "dev"}}),
+				zap.Ints("ids", []int{1, 2, 3}),
+				zap.Duration("took", 1500*time.Millisecond),
+				zap.Namespace("req"),
+				zap.Bool("ok", true),
+			},
+			expected: []FixField{
+				{"path", `c:\tmp`},
+				{"a=b", "x=y"},
+				{"user.name", "bob"},
+				{"user.roles", "[admin,dev]"},
+				{"ids", "[1,2,3]"},
+				{"took", "1.5"},
+				{"req.ok", "true"},
+			},
+		},
+		{
+			desc: "fix message",
+			ent: zapcore.Entry{
+				Time:    time.Date(2018, 6, 19, 16, 33, 42, 0, time.UTC),
+				Message: "sent",
+			},
+			fields:   []zapcore.Field{zap.String("msg", order)},
+			expected: []FixField{{"msg", order}},
+		},
+		{
+			desc: "decoded fix message",
+			opts: []FixOption{WithFixDecode()},
+			ent: zapcore.Entry{
+				Time:    time.Date(2018, 6, 19, 16, 33, 42, 0, time.UTC),
+				Message: "sent",
+			},
+			fields:   []zapcore.Field{zap.String("msg", order)},
+			expected: []FixField{{"msg", order}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			enc := NewFixEncoder(fixTestConfig, tt.opts...)
+			buf, err := enc.EncodeEntry(tt.ent, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			line := buf.String()
+			buf.Free()
+			if strings.Count(line, "\n") != 1 {
+				t.Fatalf("line %q", line)
+			}
+
+			record, err := NewFixParser(fixTestConfig, tt.opts...).Parse(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !record.Time.Equal(tt.ent.Time.Truncate(time.Millisecond)) {
+				t.Errorf("time %v, want %v", record.Time, tt.ent.Time)
+			}
+			if record.Message != tt.ent.Message {
+				t.Errorf("message %q, want %q", record.Message, tt.ent.Message)
+			}
+			if record.Logger != tt.ent.LoggerName {
+				t.Errorf("logger %q, want %q", record.Logger, tt.ent.LoggerName)
+			}
+			if len(record.Fields) != len(tt.expected) {
+				t.Fatalf("fields %v, want %v", record.Fields, tt.expected)
+			}
+			for i, f := range tt.expected {
+				if record.Fields[i] != f {
+					t.Errorf("field %d %q, want %q", i, record.Fields[i], f)
+				}
+			}
+		})
+	}
+}
+
+func TestFixParseWith(t *testing.T) {
+	enc := NewFixEncoder(fixTestConfig)
+	enc.AddString("service", "gateway")
+	buf, err := enc.EncodeEntry(zapcore.Entry{Time: time.Now(), Message: "hello"},
+		[]zapcore.Field{zap.Int("n", 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+
+	var records []*FixRecord
+	err = NewFixParser(fixTestConfig).Scan(strings.NewReader(buf.String()+buf.String()),
+		func(r *FixRecord) error {
+			records = append(records, r)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("records %d", len(records))
+	}
+	if v, _ := records[1].Field("service"); v != "gateway" {
+		t.Errorf("service %q", v)
+	}
+	if v, _ := records[1].Field("n"); v != "1" {
+		t.Errorf("n %q", v)
+	}
+}
+
+func TestDecodeFix(t *testing.T) {
+	msg := "8=FIX.4.2\x0135=A\x0198=0\x01108=30\x019999=x\x0110=000\x01"
+	decoded := DecodeFix(msg)
+	expected := "8(BeginString)=FIX.4.2|35(MsgType)=A|98(EncryptMethod)=0|108(HeartBtInt)=30|9999=x|10(CheckSum)=000"
+	if decoded != expected {
+		t.Errorf("decoded %q, want %q", decoded, expected)
+	}
+	if EncodeFix(decoded) != msg {
+		t.Errorf("encoded %q, want %q", EncodeFix(decoded), msg)
+	}
+	if DecodeFix("not fix") != "not fix" {
+		t.Error("decoded a plain string")
+	}
+	if DecodeFix("8=FIX.4.4\x0122=8\x01") != "8(BeginString)=FIX.4.4|22(SecurityIDSource)=8" {
+		t.Errorf("4.4 %q", DecodeFix("8=FIX.4.4\x0122=8\x01"))
+	}
+}
@@ -0,0 +1,389 @@
+package encoder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _gelfPool = sync.Pool{New: func() interface{} {
+	return &gelfEncoder{}
+}}
+
+func getGELFEncoder() *gelfEncoder {
+	return _gelfPool.Get().(*gelfEncoder)
+}
+
+func putGELFEncoder(enc *gelfEncoder) {
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.prefix = ""
+	_gelfPool.Put(enc)
+}
+
+// GELFLevels are the syslog severities of the levels.
+var GELFLevels = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  1,
+	zapcore.FatalLevel:  0,
+}
+
+// gelfEncoder writes GELF 1.1 messages. The fields are additional fields
+// prefixed by _, nested keys are joined by dots, arrays and reflected
+// values are written as json strings, GELF only allowing strings and
+// numbers.
+type gelfEncoder struct {
+	*zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	host   string
+	prefix string // of nested keys
+}
+
+// GELFOption configures the GELF encoder.
+type GELFOption func(*gelfEncoder)
+
+// WithGELFHost sets the host of the messages, os.Hostname by default.
+func WithGELFHost(host string) GELFOption {
+	return func(enc *gelfEncoder) {
+		enc.host = host
+	}
+}
+
+// NewGELFEncoder ...
+func NewGELFEncoder(cfg zapcore.EncoderConfig, opts ...GELFOption) zapcore.Encoder {
+	enc := &gelfEncoder{
+		EncoderConfig: &cfg,
+		buf:           _bufferPool.Get(),
+	}
+	enc.host, _ = os.Hostname()
+	for _, o := range opts {
+		o(enc)
+	}
+	if enc.host == "" {
+		enc.host = "localhost"
+	}
+	return enc
+}
+
+func (enc *gelfEncoder) clone() *gelfEncoder {
+	clone := getGELFEncoder()
+	clone.EncoderConfig = enc.EncoderConfig
+	clone.host = enc.host
+	clone.prefix = enc.prefix
+	clone.buf = _bufferPool.Get()
+	return clone
+}
+
+func (enc *gelfEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+
+	final.buf.AppendString(`{"version":"1.1","host":`)
+	appendJSONString(final.buf, final.host)
+	final.buf.AppendString(`,"short_message":`)
+	appendJSONString(final.buf, ent.Message)
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.buf.AppendString(`,"full_message":`)
+		appendJSONString(final.buf, ent.Message+"\n"+ent.Stack)
+	}
+
+	// seconds since epoch with the milliseconds
+	final.buf.AppendString(`,"timestamp":`)
+	ms := ent.Time.UnixNano() / int64(time.Millisecond)
+	final.buf.AppendInt(ms / 1000)
+	final.buf.AppendByte('.')
+	final.buf.AppendString(fmt.Sprintf("%03d", ms%1000))
+
+	final.buf.AppendString(`,"level":`)
+	level, ok := GELFLevels[ent.Level]
+	if !ok {
+		level = 6
+	}
+	final.buf.AppendInt(int64(level))
+
+	// the level name is not added, Graylog would read _level as level
+	final.prefix = ""
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.AddString(final.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && final.CallerKey != "" {
+		final.addKey(final.CallerKey)
+		cur := final.buf.Len()
+		final.EncodeCaller(ent.Caller, final)
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Caller.String())
+		}
+	}
+
+	final.buf.Write(enc.buf.Bytes())
+	final.prefix = enc.prefix
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.buf.AppendByte('}')
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	ret := final.buf
+	putGELFEncoder(final)
+	return ret, nil
+}
+
+// addKey writes the key of an additional field, its characters out of
+// [\w.-] replaced by _, _id being reserved.
+func (enc *gelfEncoder) addKey(k string) {
+	k = enc.prefix + k
+	if k == "id" {
+		k = "id_"
+	}
+	enc.buf.AppendString(`,"_`)
+	for i := 0; i < len(k); i++ {
+		c := k[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9',
+			c == '_', c == '.', c == '-':
+			enc.buf.AppendByte(c)
+		default:
+			enc.buf.AppendByte('_')
+		}
+	}
+	enc.buf.AppendString(`":`)
+}
+
+// AddArray implements ObjectEncoder.
+func (enc *gelfEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	s, err := marshalValue(func(m zapcore.ObjectEncoder) error { return m.AddArray("", v) })
+	if err != nil {
+		return err
+	}
+	enc.AddString(k, s)
+	return nil
+}
+
+// AddObject implements ObjectEncoder.
+func (enc *gelfEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	prefix := enc.prefix
+	enc.prefix += k + "."
+	err := v.MarshalLogObject(enc)
+	enc.prefix = prefix
+	return err
+}
+
+// AddBinary implements ObjectEncoder.
+func (enc *gelfEncoder) AddBinary(k string, v []byte) {
+	enc.AddString(k, base64.StdEncoding.EncodeToString(v))
+}
+
+// AddByteString implements ObjectEncoder.
+func (enc *gelfEncoder) AddByteString(k string, v []byte) {
+	enc.AddString(k, string(v))
+}
+
+// AddBool implements ObjectEncoder.
+func (enc *gelfEncoder) AddBool(k string, v bool) {
+	enc.addKey(k)
+	enc.AppendBool(v)
+}
+
+// AddDuration implements ObjectEncoder.
+func (enc *gelfEncoder) AddDuration(k string, v time.Duration) {
+	enc.addKey(k)
+	enc.AppendDuration(v)
+}
+
+// AddComplex128 implements ObjectEncoder.
+func (enc *gelfEncoder) AddComplex128(k string, v complex128) {
+	enc.addKey(k)
+	enc.AppendComplex128(v)
+}
+
+// AddComplex64 implements ObjectEncoder.
+func (enc *gelfEncoder) AddComplex64(k string, v complex64) {
+	enc.addKey(k)
+	enc.AppendComplex64(v)
+}
+
+// AddFloat64 implements ObjectEncoder.
+func (enc *gelfEncoder) AddFloat64(k string, v float64) {
+	enc.addKey(k)
+	enc.AppendFloat64(v)
+}
+
+// AddFloat32 implements ObjectEncoder.
+func (enc *gelfEncoder) AddFloat32(k string, v float32) {
+	enc.addKey(k)
+	enc.AppendFloat32(v)
+}
+
+// AddInt implements ObjectEncoder.
+func (enc *gelfEncoder) AddInt(k string, v int) { enc.AddInt64(k, int64(v)) }
+
+// AddInt64 implements ObjectEncoder.
+func (enc *gelfEncoder) AddInt64(k string, v int64) {
+	enc.addKey(k)
+	enc.AppendInt64(v)
+}
+
+// AddInt32 implements ObjectEncoder.
+func (enc *gelfEncoder) AddInt32(k string, v int32) { enc.AddInt64(k, int64(v)) }
+
+// AddInt16 implements ObjectEncoder.
+func (enc *gelfEncoder) AddInt16(k string, v int16) { enc.AddInt64(k, int64(v)) }
+
+// AddInt8 implements ObjectEncoder.
+func (enc *gelfEncoder) AddInt8(k string, v int8) { enc.AddInt64(k, int64(v)) }
+
+// AddString implements ObjectEncoder.
+func (enc *gelfEncoder) AddString(k string, v string) {
+	enc.addKey(k)
+	enc.AppendString(v)
+}
+
+// AddTime implements ObjectEncoder.
+func (enc *gelfEncoder) AddTime(k string, v time.Time) {
+	enc.addKey(k)
+	enc.AppendTime(v)
+}
+
+// AddUint implements ObjectEncoder.
+func (enc *gelfEncoder) AddUint(k string, v uint) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint64 implements ObjectEncoder.
+func (enc *gelfEncoder) AddUint64(k string, v uint64) {
+	enc.addKey(k)
+	enc.AppendUint64(v)
+}
+
+// AddUint32 implements ObjectEncoder.
+func (enc *gelfEncoder) AddUint32(k string, v uint32) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint16 implements ObjectEncoder.
+func (enc *gelfEncoder) AddUint16(k string, v uint16) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint8 implements ObjectEncoder.
+func (enc *gelfEncoder) AddUint8(k string, v uint8) { enc.AddUint64(k, uint64(v)) }
+
+// AddUintptr implements ObjectEncoder.
+func (enc *gelfEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+// AddReflected implements ObjectEncoder.
+func (enc *gelfEncoder) AddReflected(k string, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		enc.AddString(k, "null")
+		return nil
+	case string:
+		enc.AddString(k, v)
+		return nil
+	case fmt.Stringer:
+		enc.AddString(k, v.String())
+		return nil
+	}
+
+	s, err := marshalValue(func(m zapcore.ObjectEncoder) error { return m.AddReflected("", v) })
+	if err != nil {
+		s = fmt.Sprintf("%+v", v)
+	}
+	enc.AddString(k, s)
+	return nil
+}
+
+// OpenNamespace implements ObjectEncoder.
+func (enc *gelfEncoder) OpenNamespace(k string) {
+	enc.prefix += k + "."
+}
+
+func (enc *gelfEncoder) AppendByteString(val []byte) {
+	enc.AppendString(string(val))
+}
+
+func (enc *gelfEncoder) AppendString(val string) {
+	appendJSONString(enc.buf, val)
+}
+
+// AppendBool writes val as a string.
+func (enc *gelfEncoder) AppendBool(val bool) {
+	enc.buf.AppendByte('"')
+	enc.buf.AppendBool(val)
+	enc.buf.AppendByte('"')
+}
+
+func (enc *gelfEncoder) AppendDuration(val time.Duration) {
+	cur := enc.buf.Len()
+	enc.EncodeDuration(val, enc)
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(int64(val))
+	}
+}
+
+func (enc *gelfEncoder) AppendTime(val time.Time) {
+	cur := enc.buf.Len()
+	enc.EncodeTime(val, enc)
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(val.UnixNano())
+	}
+}
+
+func (enc *gelfEncoder) AppendComplex64(v complex64) { enc.AppendComplex128(complex128(v)) }
+func (enc *gelfEncoder) AppendFloat64(v float64)     { enc.appendFloat(v, 64) }
+func (enc *gelfEncoder) AppendFloat32(v float32)     { enc.appendFloat(float64(v), 32) }
+func (enc *gelfEncoder) AppendInt(v int)             { enc.AppendInt64(int64(v)) }
+func (enc *gelfEncoder) AppendInt32(v int32)         { enc.AppendInt64(int64(v)) }
+func (enc *gelfEncoder) AppendInt16(v int16)         { enc.AppendInt64(int64(v)) }
+func (enc *gelfEncoder) AppendInt8(v int8)           { enc.AppendInt64(int64(v)) }
+func (enc *gelfEncoder) AppendUint(v uint)           { enc.AppendUint64(uint64(v)) }
+func (enc *gelfEncoder) AppendUint32(v uint32)       { enc.AppendUint64(uint64(v)) }
+func (enc *gelfEncoder) AppendUint16(v uint16)       { enc.AppendUint64(uint64(v)) }
+func (enc *gelfEncoder) AppendUint8(v uint8)         { enc.AppendUint64(uint64(v)) }
+func (enc *gelfEncoder) AppendUintptr(v uintptr)     { enc.AppendUint64(uint64(v)) }
+
+func (enc *gelfEncoder) appendFloat(val float64, bitSize int) {
+	switch {
+	case math.IsNaN(val):
+		enc.buf.AppendString(`"NaN"`)
+	case math.IsInf(val, 1):
+		enc.buf.AppendString(`"+Inf"`)
+	case math.IsInf(val, -1):
+		enc.buf.AppendString(`"-Inf"`)
+	default:
+		enc.buf.AppendFloat(val, bitSize)
+	}
+}
+
+func (enc *gelfEncoder) AppendInt64(val int64) {
+	enc.buf.AppendInt(val)
+}
+
+func (enc *gelfEncoder) AppendUint64(val uint64) {
+	enc.buf.AppendUint(val)
+}
+
+func (enc *gelfEncoder) AppendComplex128(val complex128) {
+	r, i := float64(real(val)), float64(imag(val))
+	enc.buf.AppendByte('"')
+	enc.buf.AppendFloat(r, 64)
+	if i >= 0 {
+		enc.buf.AppendByte('+')
+	}
+	enc.buf.AppendFloat(i, 64)
+	enc.buf.AppendByte('i')
+	enc.buf.AppendByte('"')
+}
@@ -0,0 +1,73 @@
+package encoder
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGELFEncodeEntry(t *testing.T) {
+	enc := NewGELFEncoder(zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		TimeKey:        "time",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}, WithGELFHost("web-1"))
+	enc.AddString("service", "gateway")
+
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2018, 6, 19, 16, 33, 42, int(250*time.Millisecond), time.UTC),
+		LoggerName: "bob",
+		Message:    "lob law",
+		Stack:      "main.main\n\tmain.go:12",
+	}
+	fields := []zapcore.Field{
+		zap.String("id", "42"),
+		zap.String("so", "passes"),
+		zap.Int("answer", 42),
+		zap.Bool("ok", false),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Object("user", fixUser{"bob", []string{"admin"}}),
+		zap.String("bad key!", "x"),
+	}
+
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+
+	expected := `{"version":"1.1","host":"web-1","short_message":"lob law",` +
+		`"full_message":"lob law\nmain.main\n\tmain.go:12","timestamp":1529426022.250,"level":3,` +
+		`"_logger":"bob","_service":"gateway","_id_":"42","_so":"passes","_answer":42,"_ok":"false",` +
+		`"_ids":"[1,2]","_user.name":"bob","_user.roles":"[\"admin\"]","_bad_key_":"x"}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, buf.String())
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, format := range []string{"", "json", "console", "text", "fix", "logfmt", "GELF"} {
+		if _, err := New(format, zapcore.EncoderConfig{}); err != nil {
+			t.Errorf("%s: %v", format, err)
+		}
+	}
+	if _, err := New("xml", zapcore.EncoderConfig{}); err == nil {
+		t.Error("xml encoder")
+	}
+}
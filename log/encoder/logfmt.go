@@ -0,0 +1,368 @@
+package encoder
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var _logfmtPool = sync.Pool{New: func() interface{} {
+	return &logfmtEncoder{}
+}}
+
+func getLogfmtEncoder() *logfmtEncoder {
+	return _logfmtPool.Get().(*logfmtEncoder)
+}
+
+func putLogfmtEncoder(enc *logfmtEncoder) {
+	enc.EncoderConfig = nil
+	enc.buf = nil
+	enc.prefix = ""
+	_logfmtPool.Put(enc)
+}
+
+// logfmtEncoder writes space separated key=value pairs, the values quoted
+// if they hold spaces, quotes, = or control characters. Nested keys are
+// joined by dots, arrays and reflected values are written as json.
+type logfmtEncoder struct {
+	*zapcore.EncoderConfig
+	buf    *buffer.Buffer
+	prefix string // of nested keys
+}
+
+// NewLogfmtEncoder ...
+func NewLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{
+		EncoderConfig: &cfg,
+		buf:           _bufferPool.Get(),
+	}
+}
+
+func (enc *logfmtEncoder) clone() *logfmtEncoder {
+	clone := getLogfmtEncoder()
+	clone.EncoderConfig = enc.EncoderConfig
+	clone.prefix = enc.prefix
+	clone.buf = _bufferPool.Get()
+	return clone
+}
+
+func (enc *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := enc.clone()
+	clone.buf.Write(enc.buf.Bytes())
+	return clone
+}
+
+func (enc *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	final := enc.clone()
+	final.prefix = ""
+
+	if final.TimeKey != "" {
+		final.AddTime(final.TimeKey, ent.Time)
+	}
+	if final.LevelKey != "" {
+		final.addKey(final.LevelKey)
+		cur := final.buf.Len()
+		final.EncodeLevel(ent.Level, final)
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Level.String())
+		}
+	}
+	if ent.LoggerName != "" && final.NameKey != "" {
+		final.AddString(final.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && final.CallerKey != "" {
+		final.addKey(final.CallerKey)
+		cur := final.buf.Len()
+		final.EncodeCaller(ent.Caller, final)
+		if cur == final.buf.Len() {
+			final.AppendString(ent.Caller.String())
+		}
+	}
+	if final.MessageKey != "" {
+		final.AddString(final.MessageKey, ent.Message)
+	}
+
+	if enc.buf.Len() > 0 {
+		if final.buf.Len() > 0 {
+			final.buf.AppendByte(' ')
+		}
+		final.buf.Write(enc.buf.Bytes())
+	}
+	final.prefix = enc.prefix
+	for i := range fields {
+		fields[i].AddTo(final)
+	}
+	final.prefix = ""
+	if ent.Stack != "" && final.StacktraceKey != "" {
+		final.AddString(final.StacktraceKey, ent.Stack)
+	}
+
+	if final.LineEnding != "" {
+		final.buf.AppendString(final.LineEnding)
+	} else {
+		final.buf.AppendString(zapcore.DefaultLineEnding)
+	}
+	ret := final.buf
+	putLogfmtEncoder(final)
+	return ret, nil
+}
+
+func (enc *logfmtEncoder) addKey(k string) {
+	if enc.buf.Len() > 0 {
+		enc.buf.AppendByte(' ')
+	}
+	k = enc.prefix + k
+	if k == "" {
+		k = `""`
+	}
+	for i := 0; i < len(k); i++ {
+		// the keys are unquoted, their special characters are replaced
+		if c := k[i]; c <= ' ' || c == '=' || c == '"' {
+			enc.buf.AppendByte('_')
+		} else {
+			enc.buf.AppendByte(c)
+		}
+	}
+	enc.buf.AppendByte('=')
+}
+
+// AddArray implements ObjectEncoder.
+func (enc *logfmtEncoder) AddArray(k string, v zapcore.ArrayMarshaler) error {
+	s, err := marshalValue(func(m zapcore.ObjectEncoder) error { return m.AddArray("", v) })
+	if err != nil {
+		return err
+	}
+	enc.AddString(k, s)
+	return nil
+}
+
+// AddObject implements ObjectEncoder.
+func (enc *logfmtEncoder) AddObject(k string, v zapcore.ObjectMarshaler) error {
+	prefix := enc.prefix
+	enc.prefix += k + "."
+	err := v.MarshalLogObject(enc)
+	enc.prefix = prefix
+	return err
+}
+
+// AddBinary implements ObjectEncoder.
+func (enc *logfmtEncoder) AddBinary(k string, v []byte) {
+	enc.AddString(k, base64.StdEncoding.EncodeToString(v))
+}
+
+// AddByteString implements ObjectEncoder.
+func (enc *logfmtEncoder) AddByteString(k string, v []byte) {
+	enc.addKey(k)
+	enc.AppendByteString(v)
+}
+
+// AddBool implements ObjectEncoder.
+func (enc *logfmtEncoder) AddBool(k string, v bool) {
+	enc.addKey(k)
+	enc.AppendBool(v)
+}
+
+// AddDuration implements ObjectEncoder.
+func (enc *logfmtEncoder) AddDuration(k string, v time.Duration) {
+	enc.addKey(k)
+	enc.AppendDuration(v)
+}
+
+// AddComplex128 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddComplex128(k string, v complex128) {
+	enc.addKey(k)
+	enc.AppendComplex128(v)
+}
+
+// AddComplex64 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddComplex64(k string, v complex64) {
+	enc.addKey(k)
+	enc.AppendComplex64(v)
+}
+
+// AddFloat64 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddFloat64(k string, v float64) {
+	enc.addKey(k)
+	enc.AppendFloat64(v)
+}
+
+// AddFloat32 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddFloat32(k string, v float32) {
+	enc.addKey(k)
+	enc.AppendFloat32(v)
+}
+
+// AddInt implements ObjectEncoder.
+func (enc *logfmtEncoder) AddInt(k string, v int) { enc.AddInt64(k, int64(v)) }
+
+// AddInt64 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddInt64(k string, v int64) {
+	enc.addKey(k)
+	enc.AppendInt64(v)
+}
+
+// AddInt32 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddInt32(k string, v int32) { enc.AddInt64(k, int64(v)) }
+
+// AddInt16 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddInt16(k string, v int16) { enc.AddInt64(k, int64(v)) }
+
+// AddInt8 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddInt8(k string, v int8) { enc.AddInt64(k, int64(v)) }
+
+// AddString implements ObjectEncoder.
+func (enc *logfmtEncoder) AddString(k string, v string) {
+	enc.addKey(k)
+	enc.AppendString(v)
+}
+
+// AddTime implements ObjectEncoder.
+func (enc *logfmtEncoder) AddTime(k string, v time.Time) {
+	enc.addKey(k)
+	enc.AppendTime(v)
+}
+
+// AddUint implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUint(k string, v uint) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint64 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUint64(k string, v uint64) {
+	enc.addKey(k)
+	enc.AppendUint64(v)
+}
+
+// AddUint32 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUint32(k string, v uint32) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint16 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUint16(k string, v uint16) { enc.AddUint64(k, uint64(v)) }
+
+// AddUint8 implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUint8(k string, v uint8) { enc.AddUint64(k, uint64(v)) }
+
+// AddUintptr implements ObjectEncoder.
+func (enc *logfmtEncoder) AddUintptr(k string, v uintptr) { enc.AddUint64(k, uint64(v)) }
+
+// AddReflected implements ObjectEncoder.
+func (enc *logfmtEncoder) AddReflected(k string, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		enc.AddString(k, "null")
+		return nil
+	case string:
+		enc.AddString(k, v)
+		return nil
+	case fmt.Stringer:
+		enc.AddString(k, v.String())
+		return nil
+	}
+
+	s, err := marshalValue(func(m zapcore.ObjectEncoder) error { return m.AddReflected("", v) })
+	if err != nil {
+		s = fmt.Sprintf("%+v", v)
+	}
+	enc.AddString(k, s)
+	return nil
+}
+
+// OpenNamespace implements ObjectEncoder.
+func (enc *logfmtEncoder) OpenNamespace(k string) {
+	enc.prefix += k + "."
+}
+
+func (enc *logfmtEncoder) AppendByteString(val []byte) {
+	enc.AppendString(string(val))
+}
+
+// AppendString writes val, quoted if needed.
+func (enc *logfmtEncoder) AppendString(val string) {
+	if !logfmtQuote(val) {
+		enc.buf.AppendString(val)
+		return
+	}
+	appendJSONString(enc.buf, val)
+}
+
+func (enc *logfmtEncoder) AppendBool(val bool) {
+	enc.buf.AppendBool(val)
+}
+
+func (enc *logfmtEncoder) AppendDuration(val time.Duration) {
+	cur := enc.buf.Len()
+	enc.EncodeDuration(val, enc)
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(int64(val))
+	}
+}
+
+func (enc *logfmtEncoder) AppendTime(val time.Time) {
+	cur := enc.buf.Len()
+	enc.EncodeTime(val, enc)
+	if cur == enc.buf.Len() {
+		enc.AppendInt64(val.UnixNano())
+	}
+}
+
+func (enc *logfmtEncoder) AppendComplex64(v complex64) { enc.AppendComplex128(complex128(v)) }
+func (enc *logfmtEncoder) AppendFloat64(v float64)     { enc.appendFloat(v, 64) }
+func (enc *logfmtEncoder) AppendFloat32(v float32)     { enc.appendFloat(float64(v), 32) }
+func (enc *logfmtEncoder) AppendInt(v int)             { enc.AppendInt64(int64(v)) }
+func (enc *logfmtEncoder) AppendInt32(v int32)         { enc.AppendInt64(int64(v)) }
+func (enc *logfmtEncoder) AppendInt16(v int16)         { enc.AppendInt64(int64(v)) }
+func (enc *logfmtEncoder) AppendInt8(v int8)           { enc.AppendInt64(int64(v)) }
+func (enc *logfmtEncoder) AppendUint(v uint)           { enc.AppendUint64(uint64(v)) }
+func (enc *logfmtEncoder) AppendUint32(v uint32)       { enc.AppendUint64(uint64(v)) }
+func (enc *logfmtEncoder) AppendUint16(v uint16)       { enc.AppendUint64(uint64(v)) }
+func (enc *logfmtEncoder) AppendUint8(v uint8)         { enc.AppendUint64(uint64(v)) }
+func (enc *logfmtEncoder) AppendUintptr(v uintptr)     { enc.AppendUint64(uint64(v)) }
+
+func (enc *logfmtEncoder) appendFloat(val float64, bitSize int) {
+	switch {
+	case math.IsNaN(val):
+		enc.buf.AppendString(`NaN`)
+	case math.IsInf(val, 1):
+		enc.buf.AppendString(`+Inf`)
+	case math.IsInf(val, -1):
+		enc.buf.AppendString(`-Inf`)
+	default:
+		enc.buf.AppendFloat(val, bitSize)
+	}
+}
+
+func (enc *logfmtEncoder) AppendInt64(val int64) {
+	enc.buf.AppendInt(val)
+}
+
+func (enc *logfmtEncoder) AppendUint64(val uint64) {
+	enc.buf.AppendUint(val)
+}
+
+func (enc *logfmtEncoder) AppendComplex128(val complex128) {
+	r, i := float64(real(val)), float64(imag(val))
+	enc.buf.AppendFloat(r, 64)
+	if i >= 0 {
+		enc.buf.AppendByte('+')
+	}
+	enc.buf.AppendFloat(i, 64)
+	enc.buf.AppendByte('i')
+}
+
+// logfmtQuote reports whether s must be quoted.
+func logfmtQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError ||
+			r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogfmtEncodeEntry(t *testing.T) {
+	enc := NewLogfmtEncoder(zapcore.EncoderConfig{
+		MessageKey:     "msg",
+		LevelKey:       "level",
+		TimeKey:        "time",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		StacktraceKey:  "stacktrace",
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	})
+	enc.AddString("service", "gateway")
+
+	ent := zapcore.Entry{
+		Level:      zapcore.WarnLevel,
+		Time:       time.Date(2018, 6, 19, 16, 33, 42, 0, time.UTC),
+		LoggerName: "bob",
+		Message:    `lob "law"`,
+		Caller:     zapcore.NewEntryCaller(0, "/src/app/main.go", 12, true),
+	}
+	fields := []zapcore.Field{
+		zap.String("so", "passes"),
+		zap.String("empty", ""),
+		zap.Int("answer", 42),
+		zap.Float64("pie", 3.14),
+		zap.Bool("ok", true),
+		zap.Duration("took", 1500*time.Millisecond),
+		zap.Error(errors.New("not found")),
+		zap.Ints("ids", []int{1, 2}),
+		zap.Object("user", fixUser{"bob", []string{"admin"}}),
+		zap.Reflect("meta", map[string]int{"a": 1}),
+		zap.Namespace("req"),
+		zap.String("path", "/a b"),
+	}
+
+	buf, err := enc.EncodeEntry(ent, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer buf.Free()
+
+	expected := `time=2018-06-19T16:33:42.000Z level=warn logger=bob caller=app/main.go:12 msg="lob \"law\"" ` +
+		`service=gateway so=passes empty="" answer=42 pie=3.14 ok=true took=1.5s error="not found" ` +
+		`ids=[1,2] user.name=bob user.roles="[\"admin\"]" meta="{\"a\":1}" req.path="/a b"` + "\n"
+	if buf.String() != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, buf.String())
+	}
+}
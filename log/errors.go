@@ -0,0 +1,141 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+)
+
+// Suffixes of the keys added to an error field by Config.ExpandErrors, e.g.
+// error_chain for the field error.
+const (
+	ErrorChainSuffix   = "_chain"   // messages of the wrapped errors
+	ErrorCodeSuffix    = "_code"    // grpc status code
+	ErrorMessageSuffix = "_message" // grpc status message
+	ErrorDetailsSuffix = "_details" // grpc status details
+)
+
+// errorCore expands the error fields written to the cores it checks, so
+// each of them keeps its own Check.
+type errorCore struct {
+	zapcore.Core
+}
+
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCore{c.Core.With(expandErrors(fields))}
+}
+
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	checked := c.Core.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	checked.ErrorOutput = zapcore.Lock(os.Stderr)
+	return ce.AddCore(ent, &checkedCore{c.Core, checked})
+}
+
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, expandErrors(fields))
+}
+
+// checkedCore writes to the cores checked by an errorCore.
+type checkedCore struct {
+	zapcore.Core
+	ce *zapcore.CheckedEntry
+}
+
+func (c *checkedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// the caller and stack are set after Check
+	c.ce.Entry = ent
+	c.ce.Write(expandErrors(fields)...)
+	return nil
+}
+
+// expandErrors adds to the error fields the messages of the errors they
+// wrap and the grpc status found in the chain, fields is returned as is if
+// there is nothing to add.
+func expandErrors(fields []zapcore.Field) []zapcore.Field {
+	var expanded []zapcore.Field
+	for i, f := range fields {
+		var more []zapcore.Field
+		if err, ok := f.Interface.(error); ok && f.Type == zapcore.ErrorType {
+			more = errorFields(f.Key, err)
+		}
+		if more == nil && expanded == nil {
+			continue
+		}
+		if expanded == nil {
+			expanded = append(make([]zapcore.Field, 0, len(fields)+len(more)), fields[:i]...)
+		}
+		expanded = append(expanded, f)
+		expanded = append(expanded, more...)
+	}
+	if expanded == nil {
+		return fields
+	}
+	return expanded
+}
+
+// errorFields returns the fields of the chain and grpc status of err.
+func errorFields(key string, err error) []zapcore.Field {
+	var fields []zapcore.Field
+	var chain []string
+	var s *status.Status
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if e != err {
+			chain = append(chain, e.Error())
+		}
+		if s == nil {
+			s = grpcStatus(e)
+		}
+	}
+	if len(chain) != 0 {
+		fields = append(fields, zap.Strings(key+ErrorChainSuffix, chain))
+	}
+	if s == nil {
+		return fields
+	}
+
+	fields = append(fields,
+		zap.Uint32(key+ErrorCodeSuffix, uint32(s.Code())),
+		zap.String(key+ErrorMessageSuffix, s.Message()),
+	)
+	if details := s.Details(); len(details) != 0 {
+		values := make([]string, len(details))
+		for i, d := range details {
+			if info, ok := d.(*epb.DebugInfo); ok {
+				values[i] = info.Detail
+			} else {
+				values[i] = fmt.Sprint(d)
+			}
+		}
+		fields = append(fields, zap.Strings(key+ErrorDetailsSuffix, values))
+	}
+	return fields
+}
+
+// grpcStatus returns the status of e, either a grpc error or its JSON
+// marshaled by the errors package when not built in, as errors.Parse does.
+func grpcStatus(e error) *status.Status {
+	if se, ok := e.(interface{ GRPCStatus() *status.Status }); ok {
+		return se.GRPCStatus()
+	}
+	msg := e.Error()
+	if !strings.HasPrefix(msg, "{") {
+		return nil
+	}
+	var sp spb.Status
+	// the OK status is no error
+	if json.Unmarshal([]byte(msg), &sp) != nil || sp.Code == 0 {
+		return nil
+	}
+	return status.FromProto(&sp)
+}
@@ -0,0 +1,114 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	epb "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCaller(t *testing.T) {
+	dir, err := ioutil.TempDir("", "caller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	old := logger
+	defer setStandard(old)
+	err = Init(&Config{
+		Level:         "debug",
+		Path:          path,
+		MaxSize:       10,
+		Format:        "logfmt",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Caller:        true,
+		CallerFull:    true,
+		Stacktrace:    "error",
+		Ring:          &RingConfig{Size: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Info("a")
+	Errorw("b")
+	StandardLogger().PrintLogger("lib", "info").Print("c")
+	Infow("d")
+	InfoCtx(context.Background(), "e")
+	// a logger stashed in the context
+	InfoCtx(NewContext(context.Background(), StandardLogger().Sugar()), "f")
+
+	entries := StandardLogger().Ring().Entries()
+	if len(entries) != 6 {
+		t.Fatalf("entries %+v", entries)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Caller, "log/errors_test.go:") {
+			t.Errorf("%s caller %s", e.Message, e.Caller)
+		}
+	}
+	if entries[0].Stack != "" || !strings.Contains(entries[1].Stack, "TestCaller") {
+		t.Errorf("stacks %q %q", entries[0].Stack, entries[1].Stack)
+	}
+
+	if err = Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if !strings.Contains(string(data), "caller="+filepath.ToSlash(wd)+"/errors_test.go:") {
+		t.Errorf("full caller %s", data)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	l, err := New(&Config{DisableStdout: true, ExpandErrors: true, Ring: &RingConfig{Size: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := status.New(codes.NotFound, "no user").WithDetails(&epb.DebugInfo{Detail: "id 7"})
+	l.Sugar().Errorw("a", "error", fmt.Errorf("load: %w", s.Err()))
+	// the errors package marshals the status when it is not built in
+	data, _ := json.Marshal(status.New(1001, "closed").Proto())
+	l.With(zap.Error(errors.New(string(data)))).Warn("b")
+	l.Sugar().Infow("c", "error", errors.New("plain"))
+
+	entries := l.Ring().Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries %+v", entries)
+	}
+	tests := []map[string]string{
+		{
+			"error_chain":   "[rpc error: code = NotFound desc = no user]",
+			"error_code":    "5",
+			"error_message": "no user",
+			"error_details": "[id 7]",
+		},
+		{"error_code": "1001", "error_message": "closed", "error_chain": ""},
+		{"error": "plain", "error_chain": "", "error_code": ""},
+	}
+	for i, fields := range tests {
+		for key, value := range fields {
+			if v, _ := entries[i].Field(key); v != value {
+				t.Errorf("%s %s: expected %q get %q", entries[i].Message, key, value, v)
+			}
+		}
+	}
+}
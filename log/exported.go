@@ -1,12 +1,55 @@
 package log
 
-//"go.uber.org/zap/zapcore"
+import (
+	"net/http"
+
+	"go.uber.org/zap/zapcore"
+)
 
 // StandardLogger ..
 func StandardLogger() *Logger {
 	return logger
 }
 
+// SetLevel sets the level of the standard logger main core.
+func SetLevel(level string) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	logger.levels.SetLevel(lvl, 0)
+	return nil
+}
+
+// GetLevel returns the level of the standard logger main core.
+func GetLevel() string {
+	return logger.levels.Level().String()
+}
+
+// SetNamedLevel overrides the level of the standard logger named name
+// and its children, an empty level removes the override.
+func SetNamedLevel(name, level string) error {
+	if level == "" {
+		logger.levels.ResetNameLevel(name)
+		return nil
+	}
+
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return err
+	}
+	logger.levels.SetNameLevel(name, lvl, 0)
+	return nil
+}
+
+// LevelHandler returns an http.Handler to get and adjust the levels of the
+// standard logger, including the one set by a later Init.
+//
+//	curl -X PUT localhost:8080/log/level -d '{"level":"debug","duration":"10m"}'
+func LevelHandler() http.Handler {
+	return levelHandler{StandardLogger}
+}
+
 // WithFields ..
 func WithFields(args ...interface{}) *SugaredLogger {
 	return With(args...)
@@ -19,74 +62,76 @@ func With(args ...interface{}) *SugaredLogger {
 
 // Debug uses fmt.Sprint to construct and log a message.
 func Debug(args ...interface{}) {
-	sugger.Debug(args...)
+	helper.Debug(args...)
 }
 
 // Info uses fmt.Sprint to construct and log a message.
 func Info(args ...interface{}) {
-	sugger.Info(args...)
+	helper.Info(args...)
 }
 
 // Warn uses fmt.Sprint to construct and log a message.
 func Warn(args ...interface{}) {
-	sugger.Warn(args...)
+	helper.Warn(args...)
 }
 
 // Error uses fmt.Sprint to construct and log a message.
 func Error(args ...interface{}) {
-	sugger.Error(args...)
+	helper.Error(args...)
 }
 
 // DPanic uses fmt.Sprint to construct and log a message. In development, the
 // logger then panics. (See DPanicLevel for details.)
 func DPanic(args ...interface{}) {
-	sugger.DPanic(args...)
+	helper.DPanic(args...)
 }
 
 // Panic uses fmt.Sprint to construct and log a message, then panics.
 func Panic(args ...interface{}) {
-	sugger.Panic(args...)
+	helper.Panic(args...)
 }
 
-// Fatal uses fmt.Sprint to construct and log a message, then calls os.Exit.
+// Fatal uses fmt.Sprint to construct and log a message, then closes the
+// logger and calls os.Exit.
 func Fatal(args ...interface{}) {
-	sugger.Fatal(args...)
+	helper.Fatal(args...)
 }
 
 // Debugf uses fmt.Sprintf to log a templated message.
 func Debugf(template string, args ...interface{}) {
-	sugger.Debugf(template, args...)
+	helper.Debugf(template, args...)
 }
 
 // Infof uses fmt.Sprintf to log a templated message.
 func Infof(template string, args ...interface{}) {
-	sugger.Infof(template, args...)
+	helper.Infof(template, args...)
 }
 
 // Warnf uses fmt.Sprintf to log a templated message.
 func Warnf(template string, args ...interface{}) {
-	sugger.Warnf(template, args...)
+	helper.Warnf(template, args...)
 }
 
 // Errorf uses fmt.Sprintf to log a templated message.
 func Errorf(template string, args ...interface{}) {
-	sugger.Errorf(template, args...)
+	helper.Errorf(template, args...)
 }
 
 // DPanicf uses fmt.Sprintf to log a templated message. In development, the
 // logger then panics. (See DPanicLevel for details.)
 func DPanicf(template string, args ...interface{}) {
-	sugger.DPanicf(template, args...)
+	helper.DPanicf(template, args...)
 }
 
 // Panicf uses fmt.Sprintf to log a templated message, then panics.
 func Panicf(template string, args ...interface{}) {
-	sugger.Panicf(template, args...)
+	helper.Panicf(template, args...)
 }
 
-// Fatalf uses fmt.Sprintf to log a templated message, then calls os.Exit.
+// Fatalf uses fmt.Sprintf to log a templated message, then closes the
+// logger and calls os.Exit.
 func Fatalf(template string, args ...interface{}) {
-	sugger.Fatalf(template, args...)
+	helper.Fatalf(template, args...)
 }
 
 // Debugw logs a message with some additional context. The variadic key-value
@@ -95,44 +140,45 @@ func Fatalf(template string, args ...interface{}) {
 // When debug-level logging is disabled, this is much faster than
 //  s.With(keysAndValues...).Debug(msg)
 func Debugw(msg string, keysAndValues ...interface{}) {
-	sugger.Debugw(msg, keysAndValues...)
+	helper.Debugw(msg, keysAndValues...)
 }
 
 // Infow logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func Infow(msg string, keysAndValues ...interface{}) {
-	sugger.Infow(msg, keysAndValues...)
+	helper.Infow(msg, keysAndValues...)
 }
 
 // Warnw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func Warnw(msg string, keysAndValues ...interface{}) {
-	sugger.Warnw(msg, keysAndValues...)
+	helper.Warnw(msg, keysAndValues...)
 }
 
 // Errorw logs a message with some additional context. The variadic key-value
 // pairs are treated as they are in With.
 func Errorw(msg string, keysAndValues ...interface{}) {
-	sugger.Errorw(msg, keysAndValues...)
+	helper.Errorw(msg, keysAndValues...)
 }
 
 // DPanicw logs a message with some additional context. In development, the
 // logger then panics. (See DPanicLevel for details.) The variadic key-value
 // pairs are treated as they are in With.
 func DPanicw(msg string, keysAndValues ...interface{}) {
-	sugger.DPanicw(msg, keysAndValues...)
+	helper.DPanicw(msg, keysAndValues...)
 }
 
 // Panicw logs a message with some additional context, then panics. The
 // variadic key-value pairs are treated as they are in With.
 func Panicw(msg string, keysAndValues ...interface{}) {
-	sugger.Panicw(msg, keysAndValues...)
+	helper.Panicw(msg, keysAndValues...)
 }
 
-// Fatalw logs a message with some additional context, then calls os.Exit. The
-// variadic key-value pairs are treated as they are in With.
+// Fatalw logs a message with some additional context, then closes the
+// logger and calls os.Exit. The variadic key-value pairs are treated as
+// they are in With.
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	sugger.Fatalw(msg, keysAndValues...)
+	helper.Fatalw(msg, keysAndValues...)
 }
 
 // Sync flushes any buffered log entries.
@@ -0,0 +1,249 @@
+package hook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Alert hosts
+var (
+	TelegramHost = "https://api.telegram.org"
+)
+
+// AlertConfig is the config shared by the alert hooks.
+type AlertConfig struct {
+	CoreConfig
+	SendConfig
+	Title string // shown in the messages, e.g. the service name
+}
+
+// TelegramConfig sends entries to a chat through a bot, see README.
+type TelegramConfig struct {
+	AlertConfig
+	Token  string
+	ChatID string
+}
+
+// DingTalkConfig sends entries to a DingTalk group robot, Secret is
+// required if the robot signs its messages.
+type DingTalkConfig struct {
+	AlertConfig
+	URL    string
+	Secret string
+}
+
+// SlackConfig sends entries to a Slack incoming webhook.
+type SlackConfig struct {
+	AlertConfig
+	URL      string
+	Channel  string
+	Username string
+}
+
+// LarkConfig sends entries to a Lark/Feishu group bot, Secret is required
+// if the bot signs its messages.
+type LarkConfig struct {
+	AlertConfig
+	URL    string
+	Secret string
+}
+
+// alertStyle is the markup of an alert message.
+type alertStyle struct {
+	escape func(string) string
+	bold   func(string) string
+	code   func(string) string
+	block  func(string) string
+	limit  int
+}
+
+var (
+	telegramStyle = &alertStyle{
+		escape: markdownEscape,
+		bold:   func(s string) string { return "*" + s + "*" },
+		code:   func(s string) string { return "`" + codeEscape(s) + "`" },
+		block:  func(s string) string { return "```\n" + codeEscape(s) + "\n```" },
+		limit:  4096,
+	}
+	dingTalkStyle = &alertStyle{
+		escape: func(s string) string { return s },
+		bold:   func(s string) string { return "**" + s + "**" },
+		code:   func(s string) string { return "`" + s + "`" },
+		block:  func(s string) string { return "\n```\n" + s + "\n```" },
+		limit:  20000,
+	}
+	slackStyle = &alertStyle{
+		escape: strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace,
+		bold:   func(s string) string { return "*" + s + "*" },
+		code:   func(s string) string { return "`" + s + "`" },
+		block:  func(s string) string { return "```" + s + "```" },
+		limit:  40000,
+	}
+	larkStyle = &alertStyle{
+		escape: func(s string) string { return s },
+		bold:   func(s string) string { return s },
+		code:   func(s string) string { return s },
+		block:  func(s string) string { return s },
+		limit:  30000,
+	}
+)
+
+var codeEscape = strings.NewReplacer("`", "\\`", `\`, `\\`).Replace
+
+// LevelEmoji returns the emoji shown for a level in the alerts.
+func LevelEmoji(level string) string {
+	switch strings.ToUpper(level) {
+	case "DEBUG":
+		return "🐛"
+	case "INFO":
+		return "ℹ️"
+	case "WARN":
+		return "⚠️"
+	case "ERROR":
+		return "❌"
+	}
+	return "🔥"
+}
+
+// render renders the level and title, then for each entry its message,
+// fields and stack trace. Entries over the limit are counted only.
+func (s *alertStyle) render(title string, data *WebHookData) string {
+	var b strings.Builder
+	b.WriteString(LevelEmoji(data.Level) + " " + s.bold(s.escape(data.Level)))
+	if title != "" {
+		b.WriteString(" " + s.escape(title))
+	}
+	if len(data.Entries) > 1 {
+		b.WriteString(s.escape(fmt.Sprintf(" (%d entries)", len(data.Entries)+data.Dropped)))
+	}
+
+	more := data.Dropped
+	for i, e := range data.Entries {
+		entry := s.entry(e)
+		if i > 0 && b.Len()+len(entry) > s.limit-64 {
+			more += len(data.Entries) - i
+			break
+		}
+		b.WriteString(entry)
+	}
+	if more > 0 {
+		b.WriteString("\n\n" + s.escape(fmt.Sprintf("... %d more", more)))
+	}
+	return b.String()
+}
+
+// entry renders an entry, its parts are truncated to fit in the limit.
+func (s *alertStyle) entry(e *WebHookEntry) string {
+	max := s.limit / 8
+	var b strings.Builder
+	b.WriteString("\n\n" + s.bold(s.escape(truncate(max, e.Message))))
+	if e.Caller != "" {
+		b.WriteString("\n" + s.code(e.Caller))
+	}
+	for _, f := range e.Fields {
+		value := truncate(max, fmt.Sprintf("%v", f.Value))
+		b.WriteString("\n" + s.code(f.Key) + s.escape(": "+value))
+	}
+	if e.Stack != "" {
+		b.WriteString("\n" + s.block(truncate(2*max, e.Stack)))
+	}
+	return b.String()
+}
+
+func (s *alertStyle) funcs(title string) template.FuncMap {
+	return template.FuncMap{
+		"alert": func(data *WebHookData) string {
+			return s.render(title, data)
+		},
+	}
+}
+
+func alertWebHook(config *AlertConfig, host, message string) *WebHookConfig {
+	return &WebHookConfig{
+		CoreConfig:  config.CoreConfig,
+		SendConfig:  config.SendConfig,
+		Host:        host,
+		Method:      MethodPOST,
+		ContentType: "application/json",
+		Message:     message,
+	}
+}
+
+// NewTelegramCore ...
+func NewTelegramCore(config *TelegramConfig, encode zapcore.EncoderConfig) (*WebHookCore, error) {
+	chatID, _ := jsonString(config.ChatID)
+	return newWebHookCore(alertWebHook(&config.AlertConfig,
+		fmt.Sprintf("%s/bot%s/sendMessage", TelegramHost, config.Token),
+		`{"chat_id":`+chatID+`,"parse_mode":"MarkdownV2","disable_web_page_preview":true,"text":{{json (alert .)}}}`,
+	), encode, telegramStyle.funcs(config.Title), nil)
+}
+
+// NewDingTalkCore ...
+func NewDingTalkCore(config *DingTalkConfig, encode zapcore.EncoderConfig) (*WebHookCore, error) {
+	var prepare func(req *http.Request)
+	if config.Secret != "" {
+		prepare = func(req *http.Request) {
+			ts := time.Now().UnixNano() / int64(time.Millisecond)
+			q := req.URL.Query()
+			q.Set("timestamp", strconv.FormatInt(ts, 10))
+			q.Set("sign", DingTalkSign(config.Secret, ts))
+			req.URL.RawQuery = q.Encode()
+		}
+	}
+	return newWebHookCore(alertWebHook(&config.AlertConfig, config.URL,
+		`{"msgtype":"markdown","markdown":{"title":{{json (truncate 64 (printf "%s %s" .Level .Message))}},"text":{{json (alert .)}}}}`,
+	), encode, dingTalkStyle.funcs(config.Title), prepare)
+}
+
+// NewSlackCore ...
+func NewSlackCore(config *SlackConfig, encode zapcore.EncoderConfig) (*WebHookCore, error) {
+	var extra string
+	if config.Channel != "" {
+		channel, _ := jsonString(config.Channel)
+		extra += `,"channel":` + channel
+	}
+	if config.Username != "" {
+		username, _ := jsonString(config.Username)
+		extra += `,"username":` + username
+	}
+	return newWebHookCore(alertWebHook(&config.AlertConfig, config.URL,
+		`{"text":{{json (alert .)}}`+extra+`}`,
+	), encode, slackStyle.funcs(config.Title), nil)
+}
+
+// NewLarkCore ...
+func NewLarkCore(config *LarkConfig, encode zapcore.EncoderConfig) (*WebHookCore, error) {
+	funcs := larkStyle.funcs(config.Title)
+	var sign string
+	if config.Secret != "" {
+		funcs["sign"] = func(ts int64) string {
+			return LarkSign(config.Secret, ts)
+		}
+		sign = `"timestamp":"{{.Now.Unix}}","sign":"{{sign .Now.Unix}}",`
+	}
+	return newWebHookCore(alertWebHook(&config.AlertConfig, config.URL,
+		`{`+sign+`"msg_type":"text","content":{"text":{{json (alert .)}}}}`,
+	), encode, funcs, nil)
+}
+
+// DingTalkSign signs a DingTalk robot request made at timestamp ts in ms.
+func DingTalkSign(secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d\n%s", ts, secret)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LarkSign signs a Lark bot request made at timestamp ts in seconds.
+func LarkSign(secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(fmt.Sprintf("%d\n%s", ts, secret)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
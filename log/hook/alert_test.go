@@ -0,0 +1,78 @@
+package hook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAlert(t *testing.T) {
+	var (
+		path  string
+		query url.Values
+		body  map[string]interface{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		path, query, body = r.URL.Path, r.URL.Query(), nil
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("invalid json %s: %v", data, err)
+		}
+	}))
+	defer srv.Close()
+
+	data := &CoreData{
+		entry: zapcore.Entry{
+			Level:   zapcore.ErrorLevel,
+			Message: "order failed (id.1)",
+			Stack:   "main.go:12 `main`",
+		},
+		fields: []zapcore.Field{zap.String("user", "bob_1"), zap.Int("qty", 2)},
+	}
+	alert := AlertConfig{CoreConfig: CoreConfig{QueueLength: 1}, Title: "orders"}
+	send := func(h *WebHookCore, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.writeData(data)
+		h.Close()
+	}
+
+	defer func(host string) { TelegramHost = host }(TelegramHost)
+	TelegramHost = srv.URL
+	send(NewTelegramCore(&TelegramConfig{AlertConfig: alert, Token: "t0k", ChatID: "-42"}, zapcore.EncoderConfig{}))
+	expected := "❌ *ERROR* orders\n\n*order failed \\(id\\.1\\)*\n`qty`: 2\n`user`: bob\\_1\n```\nmain.go:12 \\`main\\`\n```"
+	if path != "/bott0k/sendMessage" || body["chat_id"] != "-42" || body["text"] != expected {
+		t.Fatalf("unexpected telegram %s %v", path, body)
+	}
+
+	send(NewDingTalkCore(&DingTalkConfig{AlertConfig: alert, URL: srv.URL + "/robot/send?access_token=x", Secret: "SEC"}, zapcore.EncoderConfig{}))
+	ts, _ := strconv.ParseInt(query.Get("timestamp"), 10, 64)
+	if query.Get("access_token") != "x" || query.Get("sign") != DingTalkSign("SEC", ts) {
+		t.Fatalf("unexpected dingtalk query %v", query)
+	}
+	markdown := body["markdown"].(map[string]interface{})
+	if markdown["title"] != "ERROR order failed (id.1)" || !strings.Contains(markdown["text"].(string), "**order failed (id.1)**") {
+		t.Fatalf("unexpected dingtalk %v", body)
+	}
+
+	send(NewSlackCore(&SlackConfig{AlertConfig: alert, URL: srv.URL, Channel: "#alerts"}, zapcore.EncoderConfig{}))
+	if body["channel"] != "#alerts" || !strings.HasPrefix(body["text"].(string), "❌ *ERROR* orders") {
+		t.Fatalf("unexpected slack %v", body)
+	}
+
+	send(NewLarkCore(&LarkConfig{AlertConfig: alert, URL: srv.URL, Secret: "SEC"}, zapcore.EncoderConfig{}))
+	ts, _ = strconv.ParseInt(body["timestamp"].(string), 10, 64)
+	if body["msg_type"] != "text" || body["sign"] != LarkSign("SEC", ts) || time.Since(time.Unix(ts, 0)) > time.Minute {
+		t.Fatalf("unexpected lark %v", body)
+	}
+}
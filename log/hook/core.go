@@ -1,14 +1,23 @@
 package hook
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hkjojo/go-toolkits/log/encoder"
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// DefaultCloseTimeout bounds the drain of a queue by Close.
+var DefaultCloseTimeout = 5 * time.Second
+
 // Core ..
 type Core interface {
 	writeData(data *CoreData)
@@ -16,17 +25,23 @@ type Core interface {
 
 // CoreConfig default config
 type CoreConfig struct {
+	Name        string // used to adjust the level at runtime
 	QueueLength uint32
 	Filter      []string
 	Fields      map[string]string
 	Level       string
 	Off         bool
+	Dedup       *DedupConfig
+	Sampling    *SamplingConfig
+	Redact      *redact.Config
+	Format      string // encoder of the entries: json/logfmt/gelf/fix/console, default json
 }
 
 // BaseCore BaseCore
 type BaseCore struct {
-	zapcore.LevelEnabler
+	zap.AtomicLevel
 
+	name       string
 	filters    map[string]bool
 	fields     map[string]string
 	withfields []zapcore.Field
@@ -35,6 +50,21 @@ type BaseCore struct {
 	enc        zapcore.Encoder
 	out        zapcore.WriteSyncer
 	off        bool
+	state      *coreState
+	dedup      *dedup
+	sampler    *Sampler
+	redactor   *redact.Redactor
+}
+
+// coreState is shared by a core and its clones.
+type coreState struct {
+	dropped uint64
+	done    chan struct{}   // closed to stop accepting entries
+	exited  chan struct{}   // closed once the queue is drained
+	ctx     context.Context // canceled when the drain is aborted
+	cancel  context.CancelFunc
+	once    sync.Once
+	closed  int32
 }
 
 // CoreData ..
@@ -43,6 +73,14 @@ type CoreData struct {
 	fields []zapcore.Field
 }
 
+// newEncoder returns the encoder of format, json by default.
+func newEncoder(format string, encode zapcore.EncoderConfig) (zapcore.Encoder, error) {
+	if format == "" {
+		format = encoder.FormatJSON
+	}
+	return encoder.New(format, encode)
+}
+
 // With ..
 func (c *BaseCore) With(fields []zapcore.Field) zapcore.Core {
 	clone := c.clone()
@@ -59,29 +97,46 @@ func (c *BaseCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
 		}
 
 		c.filterFields(fs)
-		err := c.write(ent, MapFieldToSlice(fs))
+		fields := MapFieldToSlice(fs)
+		if c.redactor != nil {
+			ent.Message = c.redactor.String(ent.Message)
+			fields = c.redactor.Fields(fields)
+		}
+		if c.dedup != nil && c.dedup.suppress(ent, fields) {
+			return nil
+		}
+		err := c.write(ent, fields)
 		if err != nil {
 			return err
 		}
 	}
 
-	if c.LevelEnabler.Enabled(ent.Level) {
-		c.Sync()
-	}
-
 	return nil
 }
 
 // Check ..
 func (c *BaseCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	if c.off {
+	if c.off || c.isClosed() {
 		return ce
 	}
 
-	if c.Enabled(ent.Level) {
-		return ce.AddCore(ent, c)
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if c.sampler != nil && !c.sampler.Allow(ent) {
+		return ce
 	}
-	return ce
+	return ce.AddCore(ent, c)
+}
+
+// Sampler returns the sampler of the core, nil if it is not sampled.
+func (c *BaseCore) Sampler() *Sampler {
+	return c.sampler
+}
+
+// Name returns the configured name of the core.
+func (c *BaseCore) Name() string {
+	return c.name
 }
 
 // Sync ..
@@ -90,6 +145,9 @@ func (c *BaseCore) Sync() error {
 }
 
 func (c *BaseCore) write(entry zapcore.Entry, fields []zapcore.Field) (err error) {
+	if c.isClosed() {
+		return nil
+	}
 
 	select {
 	case c.queue <- &CoreData{
@@ -97,41 +155,137 @@ func (c *BaseCore) write(entry zapcore.Entry, fields []zapcore.Field) (err error
 		fields: fields,
 	}:
 	default:
-		fmt.Fprintf(os.Stderr, "log channel is full entry[%v] abort [%d]",
-			entry, len(c.queue))
-		for len(c.queue) != 0 {
-			<-c.queue
+		// drop the new entry only, reporting the first and every 1000th
+		if n := atomic.AddUint64(&c.state.dropped, 1); n%1000 == 1 {
+			fmt.Fprintf(os.Stderr, "[log] %s queue is full, %d entries dropped\n",
+				c.name, n)
 		}
 	}
 	return nil
 }
 
+// Dropped returns the number of entries dropped because the queue was full.
+func (c *BaseCore) Dropped() uint64 {
+	if c.state == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&c.state.dropped)
+}
+
 func (c *BaseCore) clone() *BaseCore {
 	return &BaseCore{
-		LevelEnabler: c.LevelEnabler,
-		enc:          c.enc.Clone(),
-		out:          c.out,
-		core:         c.core,
-		filters:      c.filters,
-		fields:       c.fields,
-		queue:        c.queue,
-		off:          c.off,
-		withfields:   c.withfields,
+		AtomicLevel: c.AtomicLevel,
+		name:        c.name,
+		enc:         c.enc.Clone(),
+		out:         c.out,
+		core:        c.core,
+		filters:     c.filters,
+		fields:      c.fields,
+		queue:       c.queue,
+		off:         c.off,
+		withfields:  c.withfields,
+		state:       c.state,
+		dedup:       c.dedup,
+		sampler:     c.sampler,
+		redactor:    c.redactor,
 	}
 }
 
 // Start ..
 func (c *BaseCore) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.state = &coreState{
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if c.dedup != nil {
+		go c.summarize()
+	}
 	go func() {
+		defer close(c.state.exited)
 		for {
 			select {
 			case entry := <-c.queue:
 				c.writeData(entry)
+			case <-c.state.done:
+				c.drain()
+				return
 			}
 		}
 	}()
 }
 
+// drain writes the entries left in the queue until it is empty or the
+// drain is aborted.
+func (c *BaseCore) drain() {
+	for {
+		select {
+		case <-c.state.ctx.Done():
+			return
+		case entry := <-c.queue:
+			c.writeData(entry)
+		default:
+			return
+		}
+	}
+}
+
+// stop stops accepting entries, the queue goroutine exits once drained.
+func (c *BaseCore) stop() {
+	if c.state == nil {
+		return
+	}
+	c.state.once.Do(func() {
+		if c.dedup != nil {
+			for _, data := range c.dedup.summaries(true) {
+				c.write(data.entry, data.fields)
+			}
+		}
+		atomic.StoreInt32(&c.state.closed, 1)
+		close(c.state.done)
+	})
+}
+
+// shutdown stops the core and waits for the queue to be drained. The drain
+// is aborted once ctx is done.
+func (c *BaseCore) shutdown(ctx context.Context) error {
+	if c.state == nil {
+		return nil
+	}
+
+	c.stop()
+	select {
+	case <-c.state.exited:
+		return nil
+	case <-ctx.Done():
+		c.state.cancel()
+		return fmt.Errorf("%v, %d entries left", ctx.Err(), len(c.queue))
+	}
+}
+
+// Shutdown stops accepting entries and writes the queued ones, it returns
+// once they are written or ctx is done.
+func (c *BaseCore) Shutdown(ctx context.Context) error {
+	return c.shutdown(ctx)
+}
+
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// closeCore shuts c down within DefaultCloseTimeout.
+func closeCore(c shutdowner) error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return c.Shutdown(ctx)
+}
+
+func (c *BaseCore) isClosed() bool {
+	return c.state != nil && atomic.LoadInt32(&c.state.closed) == 1
+}
+
 func (c *BaseCore) filter(key string) bool {
 	if c.filters == nil {
 		return false
@@ -162,6 +316,10 @@ func (c *BaseCore) getFieldString(field zapcore.Field) string {
 }
 
 func (c *BaseCore) getField(field zapcore.Field) interface{} {
+	return fieldValue(field)
+}
+
+func fieldValue(field zapcore.Field) interface{} {
 	switch field.Type {
 	case zapcore.ArrayMarshalerType,
 		zapcore.ObjectMarshalerType,
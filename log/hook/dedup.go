@@ -0,0 +1,166 @@
+package hook
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultDedupMaxEntries bounds the fingerprints tracked by a core, entries
+// are not suppressed beyond.
+var DefaultDedupMaxEntries = 10000
+
+// DedupConfig suppresses the repeats of an entry. Entries are fingerprinted
+// by level, message with digits masked, caller and Fields. The first entry
+// is written, its repeats within Window are counted and summarized once the
+// window is over.
+type DedupConfig struct {
+	Window int      // s, off if 0
+	Fields []string // fields of the fingerprint
+	Bypass []string // levels never suppressed, e.g. fatal
+}
+
+// dedup tracks the fingerprints of a core and its clones.
+type dedup struct {
+	mu      sync.Mutex
+	window  time.Duration
+	fields  []string
+	bypass  map[zapcore.Level]bool
+	entries map[uint64]*dedupEntry
+	now     func() time.Time
+}
+
+type dedupEntry struct {
+	start  time.Time
+	count  int // suppressed within the window
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+func newDedup(config *DedupConfig) *dedup {
+	if config == nil || config.Window <= 0 {
+		return nil
+	}
+
+	d := &dedup{
+		window:  time.Duration(config.Window) * time.Second,
+		fields:  config.Fields,
+		bypass:  make(map[zapcore.Level]bool),
+		entries: make(map[uint64]*dedupEntry),
+		now:     time.Now,
+	}
+	for _, l := range config.Bypass {
+		d.bypass[ParseLevel(l)] = true
+	}
+	return d
+}
+
+func (d *dedup) fingerprint(ent zapcore.Entry, fields []zapcore.Field) uint64 {
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d|", ent.Level)
+
+	// mask the digits so ids and durations don't make entries unique
+	var masked bool
+	msg := make([]byte, 0, len(ent.Message))
+	for i := 0; i < len(ent.Message); i++ {
+		if c := ent.Message[i]; c >= '0' && c <= '9' {
+			if !masked {
+				msg = append(msg, '#')
+			}
+			masked = true
+			continue
+		}
+		masked = false
+		msg = append(msg, ent.Message[i])
+	}
+	h.Write(msg)
+
+	if ent.Caller.Defined {
+		fmt.Fprintf(h, "|%s:%d", ent.Caller.File, ent.Caller.Line)
+	}
+	for _, key := range d.fields {
+		for _, f := range fields {
+			if f.Key == key {
+				fmt.Fprintf(h, "|%s=%v", key, fieldValue(f))
+				break
+			}
+		}
+	}
+	return h.Sum64()
+}
+
+// suppress reports whether the entry is a repeat within the window.
+func (d *dedup) suppress(ent zapcore.Entry, fields []zapcore.Field) bool {
+	if d.bypass[ent.Level] {
+		return false
+	}
+
+	fp := d.fingerprint(ent, fields)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.entries[fp]; ok {
+		e.count++
+		e.entry, e.fields = ent, fields
+		return true
+	}
+	if len(d.entries) < DefaultDedupMaxEntries {
+		d.entries[fp] = &dedupEntry{start: d.now(), entry: ent}
+	}
+	return false
+}
+
+// summaries returns the summaries of the windows over, or of every window
+// if all. A fingerprint is forgotten after a window without repeats.
+func (d *dedup) summaries(all bool) []*CoreData {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var datas []*CoreData
+	now := d.now()
+	for fp, e := range d.entries {
+		elapsed := now.Sub(e.start)
+		if !all && elapsed < d.window {
+			continue
+		}
+		if e.count == 0 {
+			delete(d.entries, fp)
+			continue
+		}
+
+		ent := e.entry
+		ent.Time = now
+		ent.Message = fmt.Sprintf("%s (occurred %d times in the last %s)",
+			e.entry.Message, e.count, elapsed.Round(time.Second))
+		fields := append(e.fields[:len(e.fields):len(e.fields)], zap.Int("occurrences", e.count))
+		datas = append(datas, &CoreData{entry: ent, fields: fields})
+
+		e.start, e.count, e.fields = now, 0, nil
+	}
+	return datas
+}
+
+// summarize writes the summaries until the core is stopped.
+func (c *BaseCore) summarize() {
+	tick := c.dedup.window
+	if tick > time.Second {
+		tick = time.Second
+	}
+	t := time.NewTicker(tick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			for _, data := range c.dedup.summaries(false) {
+				c.write(data.entry, data.fields)
+			}
+		case <-c.state.done:
+			return
+		}
+	}
+}
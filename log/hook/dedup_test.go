@@ -0,0 +1,88 @@
+package hook
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type recordCore struct {
+	mu    sync.Mutex
+	datas []*CoreData
+}
+
+func (r *recordCore) writeData(data *CoreData) {
+	r.mu.Lock()
+	r.datas = append(r.datas, data)
+	r.mu.Unlock()
+}
+
+func (r *recordCore) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var msgs []string
+	for _, d := range r.datas {
+		msgs = append(msgs, d.entry.Message)
+	}
+	return msgs
+}
+
+func TestDedup(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := newDedup(&DedupConfig{Window: 300, Fields: []string{"host"}, Bypass: []string{"fatal"}})
+	d.now = func() time.Time { return now }
+
+	rec := &recordCore{}
+	c := &BaseCore{
+		AtomicLevel: zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		queue:       make(chan *CoreData, 100),
+		out:         zapcore.AddSync(ioutil.Discard),
+		core:        rec,
+		dedup:       d,
+	}
+	c.start()
+
+	ent := func(lvl zapcore.Level, msg string) zapcore.Entry {
+		return zapcore.Entry{Level: lvl, Message: msg}
+	}
+	for i := 0; i < 532; i++ {
+		c.Write(ent(zapcore.ErrorLevel, "dial db failed after 3s"), []zapcore.Field{zap.String("host", "db1")})
+	}
+	c.Write(ent(zapcore.ErrorLevel, "dial db failed after 10s"), []zapcore.Field{zap.String("host", "db1")})
+	c.Write(ent(zapcore.ErrorLevel, "dial db failed after 3s"), []zapcore.Field{zap.String("host", "db2")})
+	c.Write(ent(zapcore.FatalLevel, "exit"), nil)
+	c.Write(ent(zapcore.FatalLevel, "exit"), nil)
+
+	now = now.Add(5 * time.Minute)
+	for _, data := range d.summaries(false) {
+		c.write(data.entry, data.fields)
+	}
+	// a window without repeats forgets the fingerprint
+	now = now.Add(5 * time.Minute)
+	if datas := d.summaries(false); len(datas) != 0 || len(d.entries) != 0 {
+		t.Fatalf("unexpected summaries %d, entries %d", len(datas), len(d.entries))
+	}
+	c.shutdown(context.Background())
+
+	expected := []string{
+		"dial db failed after 3s",
+		"dial db failed after 3s",
+		"exit",
+		"exit",
+		"dial db failed after 10s (occurred 532 times in the last 5m0s)",
+	}
+	msgs := rec.messages()
+	if len(msgs) != len(expected) {
+		t.Fatalf("unexpected messages %q", msgs)
+	}
+	for i := range expected {
+		if msgs[i] != expected[i] {
+			t.Fatalf("unexpected messages %q", msgs)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package hook
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultFluentTag is the tag of the events if FluentConfig.Tag is empty.
+var DefaultFluentTag = "log"
+
+// FluentConfig writes the entries to Fluentd or Fluent Bit with the
+// Forward protocol, as events of the message mode whose record holds the
+// level, message, logger, caller, stack and fields. Format is not used.
+type FluentConfig struct {
+	CoreConfig
+	NetConfig
+	Tag         string // default log
+	IntegerTime bool   // time in seconds, for Fluentd older than v0.14
+}
+
+// FluentCore ..
+type FluentCore struct {
+	*BaseCore
+
+	config *FluentConfig
+	encode zapcore.EncoderConfig
+	conn   *netConn
+}
+
+// NewFluentCore ...
+func NewFluentCore(config *FluentConfig, encode zapcore.EncoderConfig) (core *FluentCore, err error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Network == "" {
+		config.Network = NetworkTCP
+	}
+	if config.Tag == "" {
+		config.Tag = DefaultFluentTag
+	}
+	conn, err := newNetConn(config.Name, &config.NetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	core = &FluentCore{
+		BaseCore: &BaseCore{
+			queue:       make(chan *CoreData, config.QueueLength),
+			AtomicLevel: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
+			name:        config.Name,
+			enc:         zapcore.NewJSONEncoder(encode),
+			out:         zapcore.AddSync(ioutil.Discard),
+			filters:     getfilters(config.Filter),
+			fields:      CombineFields(config.Fields, nil),
+			off:         config.Off,
+			dedup:       newDedup(config.Dedup),
+			sampler:     NewSampler(config.Sampling),
+			redactor:    redactor,
+		},
+		config: config,
+		encode: encode,
+		conn:   conn,
+	}
+	core.BaseCore.core = core
+	core.start()
+	return core, nil
+}
+
+// record returns the record of the event of data.
+func (c *FluentCore) record(data *CoreData) map[string]interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range data.fields {
+		f.AddTo(enc)
+	}
+
+	record := enc.Fields
+	set := func(key string, value string) {
+		if key != "" && value != "" {
+			record[key] = value
+		}
+	}
+	set(c.encode.LevelKey, data.entry.Level.String())
+	set(c.encode.MessageKey, data.entry.Message)
+	set(c.encode.NameKey, data.entry.LoggerName)
+	set(c.encode.StacktraceKey, data.entry.Stack)
+	if data.entry.Caller.Defined {
+		set(c.encode.CallerKey, data.entry.Caller.TrimmedPath())
+	}
+	return record
+}
+
+// event returns the [tag, time, record] message of data.
+func (c *FluentCore) event(data *CoreData) []byte {
+	b := make([]byte, 0, 256)
+	b = append(b, 0x93)
+	b = appendMsgpackString(b, c.config.Tag)
+	if c.config.IntegerTime {
+		b = appendMsgpackInt(b, data.entry.Time.Unix())
+	} else {
+		b = appendMsgpackEventTime(b, data.entry.Time)
+	}
+	return appendMsgpack(b, c.record(data))
+}
+
+func (c *FluentCore) writeData(data *CoreData) {
+	c.conn.write(c.event(data))
+}
+
+// Stats returns the counters of the core.
+func (c *FluentCore) Stats() NetStats {
+	return c.conn.stats()
+}
+
+// Shutdown stops accepting entries, writes the queued ones and closes the
+// connection.
+func (c *FluentCore) Shutdown(ctx context.Context) error {
+	err := c.BaseCore.Shutdown(ctx)
+	if cerr := c.conn.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close ..
+func (c *FluentCore) Close() error {
+	return closeCore(c)
+}
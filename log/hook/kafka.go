@@ -1,131 +1,667 @@
 package hook
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/Shopify/sarama"
+	"github.com/hkjojo/go-toolkits/log/redact"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// Kafka defaults
+var (
+	DefaultKafkaLinger         = 100 * time.Millisecond
+	DefaultKafkaRetryBackoff   = 100 * time.Millisecond
+	DefaultKafkaMaxRetries     = 3
+	DefaultKafkaSpoolInterval  = 5 * time.Second
+	DefaultKafkaMaxMessageSize = 1000000 // the default message.max.bytes of the brokers
+	DefaultKafkaReplayInFlight = 100     // spooled records sent without their ack
+)
+
+// Kafka merge modes and truncate policies
+const (
+	KafkaMergeString  = "string" // data is the fields formatted as key:value
+	KafkaMergeObject  = "object" // data is an object of the fields with their types
+	KafkaTruncateData = "data"   // the largest values are cut to fit, the size before is in KafkaTruncatedKey
+	KafkaTruncateDrop = "drop"   // the entry is dropped
+)
+
+// KafkaTruncatedKey is the key of the size of a truncated entry.
+const KafkaTruncatedKey = "truncated"
+
+var errKafkaClosed = errors.New("kafka core closed")
+
 // KafkaConfig ..
 type KafkaConfig struct {
 	CoreConfig
 	Hosts     []string
 	Topic     string
-	MergeData bool
+	MergeData bool     // merges the fields but the global ones into a data string
+	Merge     string   // string as MergeData or object, off if empty
+	Promote   []string // fields kept out of data when merged
+
+	MaxMessageSize int    // bytes of an entry, default DefaultKafkaMaxMessageSize
+	Truncate       string // policy of the entries over MaxMessageSize, data (default) or drop
+
+	BatchSize    int    // messages per batch, sent when full or after Linger
+	Linger       int    // ms to wait for a batch to fill, default 100
+	PartitionKey string // field used as message key, entries of a key keep order
+	MaxRetries   int    // retries of a failed message, default 3
+	RetryBackoff int    // ms before the first retry, doubled each retry, default 100
+	SpoolDir     string // spool entries on disk while kafka is unreachable
+	SpoolMaxSize int    // spool max size：M, unlimited if 0
+}
+
+// KafkaStats counters of a KafkaCore.
+type KafkaStats struct {
+	Sent     uint64 // acknowledged by kafka, replayed included
+	Dropped  uint64 // queue full, spool full, too large or failed without spool
+	Spooled  uint64 // written to the spool
+	Replayed uint64 // sent from the spool
 }
 
 // KafkaCore ..
 type KafkaCore struct {
 	*BaseCore
 
-	prefix string
-	config *KafkaConfig
-	client sarama.AsyncProducer
+	prefix       string
+	config       *KafkaConfig
+	sarama       *sarama.Config
+	replayConfig *sarama.Config // of the replayer, nil to replay with the client
+	spool        *spool
+	merge        string
+	promote      map[string]bool
+	maxSize      int
+
+	mu        sync.RWMutex // protects client and replayer
+	client    sarama.AsyncProducer
+	replayer  sarama.AsyncProducer // without linger, nil until a replay
+	results   sync.WaitGroup
+	replaying sync.WaitGroup
+	closeOnce sync.Once
+	closed    chan struct{}
+	closeErr  error
+
+	sent     uint64
+	dropped  uint64
+	spooled  uint64
+	replayed uint64
 }
 
 // NewKafkaCore ...
 func NewKafkaCore(config *KafkaConfig, prefix string, fields map[string]string, encode zapcore.EncoderConfig) (core *KafkaCore, err error) {
+	core, err = newKafkaCore(config, prefix, fields, encode)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewAsyncProducer(config.Hosts, core.sarama)
+	if err != nil {
+		fmt.Fprintf(os.Stderr,
+			"[log] new kafka client err: %v\n", err)
+		if core.spool == nil {
+			return
+		}
+		// kafka is unreachable, spool until the producer can be created
+		err = nil
+	}
+	core.start(client)
+	return core, nil
+}
+
+func newKafkaCore(config *KafkaConfig, prefix string, fields map[string]string, encode zapcore.EncoderConfig) (core *KafkaCore, err error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(config.Format, encode)
+	if err != nil {
+		return nil, err
+	}
+
 	core = &KafkaCore{
 		BaseCore: &BaseCore{
-			queue:        make(chan *CoreData, config.QueueLength),
-			LevelEnabler: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
-			enc:          zapcore.NewJSONEncoder(encode),
-			out:          zapcore.AddSync(ioutil.Discard),
-			filters:      getfilters(config.Filter),
-			fields:       CombineFields(fields, config.Fields),
-			off:          config.Off,
+			queue:       make(chan *CoreData, config.QueueLength),
+			AtomicLevel: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
+			name:        config.Name,
+			enc:         enc,
+			out:         zapcore.AddSync(ioutil.Discard),
+			filters:     getfilters(config.Filter),
+			fields:      CombineFields(fields, config.Fields),
+			off:         config.Off,
+			dedup:       newDedup(config.Dedup),
+			sampler:     NewSampler(config.Sampling),
+			redactor:    redactor,
 		},
-		config: config,
-		prefix: prefix,
+		config:  config,
+		prefix:  prefix,
+		closed:  make(chan struct{}),
+		merge:   config.Merge,
+		promote: getfilters(config.Promote),
+		maxSize: config.MaxMessageSize,
 	}
-
 	core.BaseCore.core = core
+	if core.merge == "" && config.MergeData {
+		core.merge = KafkaMergeString
+	}
+	switch core.merge {
+	case "", KafkaMergeString, KafkaMergeObject:
+	default:
+		return nil, fmt.Errorf("kafka: unknown merge %q", config.Merge)
+	}
+	switch config.Truncate {
+	case "", KafkaTruncateData, KafkaTruncateDrop:
+	default:
+		return nil, fmt.Errorf("kafka: unknown truncate %q", config.Truncate)
+	}
+	if core.maxSize <= 0 {
+		core.maxSize = DefaultKafkaMaxMessageSize
+	}
+
 	cfg := sarama.NewConfig()
 	cfg.Producer.RequiredAcks = sarama.WaitForAll
 	cfg.Producer.Partitioner = sarama.NewRandomPartitioner
+	if config.PartitionKey != "" {
+		cfg.Producer.Partitioner = sarama.NewHashPartitioner
+	}
 	cfg.Producer.Return.Successes = true
 	cfg.Producer.Timeout = time.Second
+	if core.maxSize >= cfg.Producer.MaxMessageBytes {
+		// room for the key
+		cfg.Producer.MaxMessageBytes = core.maxSize + 1024
+	}
 
-	core.client, err = sarama.NewAsyncProducer(config.Hosts, cfg)
-	if err != nil {
-		fmt.Fprintf(os.Stderr,
-			"[log] new kafka client err: %v\n", err)
-		return
+	if config.BatchSize > 0 {
+		cfg.Producer.Flush.Messages = config.BatchSize
+		cfg.Producer.Flush.Frequency = DefaultKafkaLinger
+		if config.Linger > 0 {
+			cfg.Producer.Flush.Frequency = time.Duration(config.Linger) * time.Millisecond
+		}
 	}
 
-	go func(p sarama.AsyncProducer) {
-		errors := p.Errors()
-		success := p.Successes()
-		for {
-			select {
-			case err := <-errors:
-				if err != nil {
-					fmt.Fprintf(os.Stderr,
-						"[log] push kafka fail err: %v\n", err)
-				}
-			case <-success:
-			}
+	cfg.Producer.Retry.Max = DefaultKafkaMaxRetries
+	if config.MaxRetries > 0 {
+		cfg.Producer.Retry.Max = config.MaxRetries
+	}
+	backoff := DefaultKafkaRetryBackoff
+	if config.RetryBackoff > 0 {
+		backoff = time.Duration(config.RetryBackoff) * time.Millisecond
+	}
+	cfg.Producer.Retry.BackoffFunc = func(retries, maxRetries int) time.Duration {
+		if retries < 1 {
+			retries = 1
 		}
-	}(core.client)
-	core.start()
+		return backoff << uint(retries-1)
+	}
+	core.sarama = cfg
+	if config.BatchSize > 0 && config.SpoolDir != "" {
+		// replayed records fill batches by themselves, no need to linger
+		replay := *cfg
+		replay.Producer.Flush.Messages = 0
+		replay.Producer.Flush.Frequency = 0
+		core.replayConfig = &replay
+	}
+
+	if config.SpoolDir != "" {
+		name := config.Name
+		if name == "" {
+			name = "kafka"
+		}
+		core.spool, err = openSpool(config.SpoolDir, prefix+config.Topic+"."+name,
+			int64(config.SpoolMaxSize)*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return core, nil
 }
 
+// start starts the queue with client, which may be nil if kafka is
+// unreachable and a spool is configured.
+func (c *KafkaCore) start(client sarama.AsyncProducer) {
+	c.BaseCore.start()
+	if client != nil {
+		c.setClient(client)
+	}
+	if c.spool != nil {
+		c.replaying.Add(1)
+		go c.replayLoop()
+	}
+}
+
+func (c *KafkaCore) setClient(client sarama.AsyncProducer) {
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	c.results.Add(1)
+	go c.handleResults(client)
+}
+
+func (c *KafkaCore) getClient() sarama.AsyncProducer {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+// handleResults handles the producer acks, failed messages are spooled.
+// Replayed messages carry a channel in Metadata to report their result.
+func (c *KafkaCore) handleResults(p sarama.AsyncProducer) {
+	defer c.results.Done()
+	errors := p.Errors()
+	success := p.Successes()
+	for errors != nil || success != nil {
+		select {
+		case perr, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
+			if done, ok := perr.Msg.Metadata.(chan error); ok {
+				done <- perr.Err
+				continue
+			}
+
+			fmt.Fprintf(os.Stderr,
+				"[log] push kafka fail err: %v\n", perr.Err)
+			key, value := encodeMessage(perr.Msg)
+			c.spoolMessage(key, value)
+		case msg, ok := <-success:
+			if !ok {
+				success = nil
+				continue
+			}
+			atomic.AddUint64(&c.sent, 1)
+			if done, ok := msg.Metadata.(chan error); ok {
+				done <- nil
+			}
+		}
+	}
+}
+
+// encode encodes the entry, in the merge modes its fields but the global
+// and promoted ones go to data. An entry over the max size is truncated or
+// dropped as configured.
 func (c *KafkaCore) encode(data *CoreData) (string, error) {
-	encode := c.enc.Clone()
-	var str string
-	for _, field := range data.fields {
-		if !c.config.MergeData {
-			field.AddTo(encode)
-			continue
+	fields := data.fields
+	if c.merge != "" {
+		var rest []zapcore.Field
+		fields, rest = c.splitFields(data.fields)
+		switch {
+		case len(rest) == 0:
+		case c.merge == KafkaMergeObject:
+			fields = append(fields, zap.Object("data", kafkaData(rest)))
+		default:
+			fields = append(fields, zap.String("data", c.mergeString(rest)))
 		}
+	}
+
+	value, err := c.encodeFields(data.entry, fields)
+	if err != nil || len(value) <= c.maxSize {
+		return value, err
+	}
+	if c.config.Truncate == KafkaTruncateDrop {
+		return "", fmt.Errorf("kafka entry of %d bytes over %d", len(value), c.maxSize)
+	}
+	return c.truncate(data.entry, data.fields, len(value))
+}
+
+func (c *KafkaCore) encodeFields(ent zapcore.Entry, fields []zapcore.Field) (string, error) {
+	buf, err := c.enc.Clone().EncodeEntry(ent, fields)
+	if err != nil {
+		return "", err
+	}
+	defer buf.Free()
+	return buf.String(), nil
+}
 
-		if _, ok := c.fields[field.Key]; ok {
-			field.AddTo(encode)
-			continue
+// splitFields splits the global and promoted fields from the ones merged in
+// data.
+func (c *KafkaCore) splitFields(fields []zapcore.Field) (top, rest []zapcore.Field) {
+	for _, field := range fields {
+		if _, ok := c.fields[field.Key]; ok || c.promote[field.Key] {
+			top = append(top, field)
+		} else {
+			rest = append(rest, field)
 		}
+	}
+	return
+}
 
+func (c *KafkaCore) mergeString(fields []zapcore.Field) string {
+	var str string
+	for _, field := range fields {
 		if str != "" {
 			str += fmt.Sprintf(" %s:%v", field.Key, c.getField(field))
 		} else {
 			str += fmt.Sprintf("%s:%v", field.Key, c.getField(field))
 		}
 	}
+	return str
+}
 
-	if str != "" {
-		zapcore.Field{Key: "data", String: str, Type: zapcore.StringType}.AddTo(encode)
+// truncate encodes the entry cut to fit, with the size it had. A data
+// string is cut, else the largest values but the global and promoted ones
+// are shortened if strings or dropped, so each field keeps its type.
+func (c *KafkaCore) truncate(ent zapcore.Entry, fields []zapcore.Field, size int) (string, error) {
+	top, rest := c.splitFields(fields)
+	truncated := zap.Int(KafkaTruncatedKey, size)
+	if c.merge == KafkaMergeString {
+		return c.truncateString(ent, append(top, zapcore.Field{}, truncated), c.mergeString(rest), size)
 	}
 
-	buf, err := encode.EncodeEntry(data.entry, nil)
+	sizes := make([]int, len(rest))
+	for i := range rest {
+		sizes[i] = kafkaFieldSize(rest[i])
+	}
+	for {
+		all := append([]zapcore.Field(nil), top...)
+		if c.merge == KafkaMergeObject {
+			all = append(all, zap.Object("data", kafkaData(rest)))
+		} else {
+			all = append(all, rest...)
+		}
+		value, err := c.encodeFields(ent, append(all, truncated))
+		if err != nil {
+			return "", err
+		}
+		over := len(value) - c.maxSize
+		if over <= 0 {
+			return value, nil
+		}
+		if len(rest) == 0 {
+			return "", fmt.Errorf("kafka entry of %d bytes over %d, fields dropped", size, c.maxSize)
+		}
+
+		largest := 0
+		for i := range sizes {
+			if sizes[i] > sizes[largest] {
+				largest = i
+			}
+		}
+		// a byte of a string takes at least one once encoded
+		if f := rest[largest]; f.Type == zapcore.StringType && len(f.String) > over {
+			rest[largest] = zap.String(f.Key, cutString(f.String, len(f.String)-over))
+			sizes[largest] = kafkaFieldSize(rest[largest])
+		} else {
+			rest = append(rest[:largest], rest[largest+1:]...)
+			sizes = append(sizes[:largest], sizes[largest+1:]...)
+		}
+	}
+}
+
+// truncateString encodes the entry with the data str cut to fit, fields
+// ending with the data and truncated ones.
+func (c *KafkaCore) truncateString(ent zapcore.Entry, fields []zapcore.Field, str string, size int) (string, error) {
+	for n := len(str); ; {
+		fields[len(fields)-2] = zap.String("data", cutString(str, n))
+		value, err := c.encodeFields(ent, fields)
+		if err != nil {
+			return "", err
+		}
+		over := len(value) - c.maxSize
+		if over <= 0 {
+			return value, nil
+		}
+		if n == 0 {
+			return "", fmt.Errorf("kafka entry of %d bytes over %d, data cut", size, c.maxSize)
+		}
+		// a byte of data takes at least one once encoded
+		if n -= over; n < 0 {
+			n = 0
+		}
+	}
+}
+
+// cutString returns the first n bytes of s at most, not cutting a rune.
+func cutString(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// kafkaFieldSize returns the size of the JSON of field.
+func kafkaFieldSize(field zapcore.Field) int {
+	buf, err := kafkaDataEncoder.EncodeEntry(zapcore.Entry{}, []zapcore.Field{field})
 	if err != nil {
-		return "", err
+		return 0
 	}
 	defer buf.Free()
-	return buf.String(), nil
+	return buf.Len()
+}
+
+// kafkaDataEncoder encodes the fields measured by kafkaFieldSize as JSON.
+var kafkaDataEncoder = zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+	EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+	EncodeDuration: zapcore.StringDurationEncoder,
+})
+
+// kafkaData is the object of the fields merged in data.
+type kafkaData []zapcore.Field
+
+func (d kafkaData) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range d {
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+func (c *KafkaCore) partitionKey(data *CoreData) []byte {
+	if c.config.PartitionKey == "" {
+		return nil
+	}
+	for _, field := range data.fields {
+		if field.Key == c.config.PartitionKey {
+			return []byte(c.getFieldString(field))
+		}
+	}
+	return nil
 }
 
 func (c *KafkaCore) writeData(data *CoreData) {
 	content, err := c.encode(data)
 	if err != nil {
+		atomic.AddUint64(&c.dropped, 1)
 		fmt.Fprintf(os.Stderr,
 			"[log] kafka encode err: %v\n", err)
 		return
 	}
-	c.write(content)
+	c.write(c.partitionKey(data), []byte(content))
+}
+
+func (c *KafkaCore) write(key, value []byte) {
+	client := c.getClient()
+	if client == nil || (c.spool != nil && c.spool.Active()) {
+		c.spoolMessage(key, value)
+		return
+	}
+	c.send(client, key, value, nil)
 }
 
-func (c *KafkaCore) write(content string) {
+func (c *KafkaCore) send(client sarama.AsyncProducer, key, value []byte, done chan error) {
 	msg := &sarama.ProducerMessage{}
 	msg.Topic = c.prefix + c.config.Topic
-	msg.Value = sarama.ByteEncoder(content)
-	c.client.Input() <- msg
+	if key != nil {
+		msg.Key = sarama.ByteEncoder(key)
+	}
+	msg.Value = sarama.ByteEncoder(value)
+	if done != nil {
+		msg.Metadata = done
+	}
+	client.Input() <- msg
 }
 
-func (c *KafkaCore) close() {
-	c.client.Close()
+func (c *KafkaCore) spoolMessage(key, value []byte) {
+	if c.spool == nil {
+		atomic.AddUint64(&c.dropped, 1)
+		return
+	}
+	if err := c.spool.append(key, value); err != nil {
+		atomic.AddUint64(&c.dropped, 1)
+		fmt.Fprintf(os.Stderr,
+			"[log] kafka spool err: %v\n", err)
+		return
+	}
+	atomic.AddUint64(&c.spooled, 1)
+}
+
+// replayLoop creates the producer if kafka was unreachable and replays the
+// spool in order once kafka accepts messages again.
+func (c *KafkaCore) replayLoop() {
+	defer c.replaying.Done()
+	t := time.NewTicker(DefaultKafkaSpoolInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-c.state.done:
+			return
+		case <-t.C:
+		}
+
+		if c.getClient() == nil {
+			client, err := sarama.NewAsyncProducer(c.config.Hosts, c.sarama)
+			if err != nil {
+				continue
+			}
+			c.setClient(client)
+		}
+		c.replay()
+	}
+}
+
+// replay replays the spool until it is empty, the records written meanwhile
+// are spooled after the replayed ones so the order is kept.
+func (c *KafkaCore) replay() error {
+	client := c.replayClient()
+	if client == nil {
+		return nil
+	}
+
+	for c.spool.Active() {
+		err := c.spool.replay(DefaultKafkaReplayInFlight, func(key, value []byte) func() error {
+			done := make(chan error, 1)
+			c.send(client, key, value, done)
+			return func() error {
+				select {
+				case err := <-done:
+					if err == nil {
+						atomic.AddUint64(&c.replayed, 1)
+					}
+					return err
+				case <-c.state.done:
+					return errKafkaClosed
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayClient returns the producer of the replayed records, the client
+// unless it lingers for batches.
+func (c *KafkaCore) replayClient() sarama.AsyncProducer {
+	client := c.getClient()
+	if client == nil || c.replayConfig == nil {
+		return client
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.replayer == nil {
+		replayer, err := sarama.NewAsyncProducer(c.config.Hosts, c.replayConfig)
+		if err != nil {
+			return nil
+		}
+		c.replayer = replayer
+		c.results.Add(1)
+		go c.handleResults(replayer)
+	}
+	return c.replayer
+}
+
+// Stats returns the counters of the core.
+func (c *KafkaCore) Stats() KafkaStats {
+	return KafkaStats{
+		Sent:     atomic.LoadUint64(&c.sent),
+		Dropped:  atomic.LoadUint64(&c.dropped) + c.Dropped(),
+		Spooled:  atomic.LoadUint64(&c.spooled),
+		Replayed: atomic.LoadUint64(&c.replayed),
+	}
+}
+
+// Shutdown stops accepting entries, writes the queued ones and flushes the
+// kafka producer. It returns once flushed or ctx is done.
+func (c *KafkaCore) Shutdown(ctx context.Context) error {
+	err := c.BaseCore.Shutdown(ctx)
+	c.closeOnce.Do(func() {
+		go func() {
+			c.closeErr = c.close()
+			close(c.closed)
+		}()
+	})
+
+	select {
+	case <-c.closed:
+		if err == nil {
+			err = c.closeErr
+		}
+	case <-ctx.Done():
+		if err == nil {
+			err = fmt.Errorf("%v, producer not flushed", ctx.Err())
+		}
+	}
+	return err
+}
+
+// close closes the producer once nothing writes to it anymore.
+func (c *KafkaCore) close() (err error) {
+	if c.state != nil {
+		<-c.state.exited
+	}
+	c.replaying.Wait()
+	c.mu.RLock()
+	replayer := c.replayer
+	c.mu.RUnlock()
+	if replayer != nil {
+		replayer.Close()
+	}
+	if client := c.getClient(); client != nil {
+		err = client.Close()
+	}
+	c.results.Wait()
+	if c.spool != nil {
+		c.spool.close()
+	}
+	return
+}
+
+// Close shuts the core down within DefaultCloseTimeout.
+func (c *KafkaCore) Close() error {
+	return closeCore(c)
+}
+
+func encodeMessage(msg *sarama.ProducerMessage) (key, value []byte) {
+	if msg.Key != nil {
+		key, _ = msg.Key.Encode()
+	}
+	if msg.Value != nil {
+		value, _ = msg.Value.Encode()
+	}
+	return
 }
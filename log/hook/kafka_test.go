@@ -2,89 +2,290 @@ package hook
 
 import (
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 func TestKafka(t *testing.T) {
-	h, err := NewKafkaCore(&KafkaConfig{
-		Hosts: []string{"localhost:9092"},
-		Topic: "app-log",
-		CoreConfig: CoreConfig{
-			QueueLength: 100000,
-			Level:       "info",
-			Off:         false,
-			Filter:      []string{"test"},
-		},
-		MergeData: true,
-	},
-		"what-gotrading-engine-",
-		map[string]string{
-			"services": "app",
-		},
-		zapcore.EncoderConfig{
-			TimeKey:        "time",
-			LevelKey:       "level",
-			NameKey:        "logger",
-			CallerKey:      "caller",
-			MessageKey:     "msg",
-			StacktraceKey:  "stacktrace",
-			LineEnding:     zapcore.DefaultLineEnding,
-			EncodeLevel:    zapcore.CapitalLevelEncoder,
-			EncodeTime:     zapcore.RFC3339TimeEncoder,
-			EncodeDuration: zapcore.SecondsDurationEncoder,
-			EncodeCaller:   zapcore.ShortCallerEncoder,
-		},
-	)
-	if err != nil {
-		t.Error(err)
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "time",
+		LevelKey:       "level",
+		NameKey:        "logger",
+		CallerKey:      "caller",
+		MessageKey:     "msg",
+		StacktraceKey:  "stacktrace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeTime:     zapcore.RFC3339TimeEncoder,
+		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+	entry := zapcore.Entry{
+		Level:      zapcore.InfoLevel,
+		Time:       time.Date(2018, 6, 19, 16, 33, 42, 99, time.UTC),
+		LoggerName: "bob",
+		Message:    "lob law",
+	}
+	pointer := &struct{}{}
+	fields := []zapcore.Field{
+		zap.String("name", "user"),
+		zap.Int("age", 12),
+		zap.Float64("cash", 1000.12),
+		zap.Reflect("null_value", nil),
+		zap.Time("create_time", time.Date(2018, 6, 19, 16, 33, 42, 99, time.UTC)),
+		zap.Reflect("array_with_null_elements", []interface{}{pointer, nil, (*struct{})(nil), 2}),
+		zap.Error(errors.New("user not found")),
+		zap.Reflect("data", struct {
+			string
+			int
+		}{"i am a good student", 10}),
 	}
 
 	tests := []struct {
 		desc     string
+		config   KafkaConfig
+		fields   []zapcore.Field
 		expected string
-		data     *CoreData
 	}{
 		{
-			desc:     "log",
-			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","data":"name:user age:12 cash:1000.12 null_value:<nil> create_time:2018-06-19 16:33:42.000000099 +0000 UTC array_with_null_elements:[0x197c9f0 <nil> <nil> 2] error:user not found data:{i am a good student 10}"}`,
-			data: &CoreData{
-				entry: zapcore.Entry{
-					Level:      zapcore.InfoLevel,
-					Time:       time.Date(2018, 6, 19, 16, 33, 42, 99, time.UTC),
-					LoggerName: "bob",
-					Message:    "lob law",
-				},
-				fields: []zapcore.Field{
-					zap.String("name", "user"),
-					zap.Int("age", 12),
-					zap.Float64("cash", 1000.12),
-					zap.Reflect("null_value", nil),
-					zap.Time("create_time", time.Date(2018, 6, 19, 16, 33, 42, 99, time.UTC)),
-					zap.Reflect("array_with_null_elements", []interface{}{&struct{}{}, nil, (*struct{})(nil), 2}),
-					zap.Error(errors.New("user not found")),
-					zap.Reflect("data", struct {
-						string
-						int
-					}{"i am a good student", 10}),
-				},
-			},
+			desc:   "log",
+			config: KafkaConfig{MergeData: true},
+			fields: fields,
+			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","data":"name:user age:12 cash:1000.12 null_value:<nil> create_time:2018-06-19 16:33:42.000000099 +0000 UTC array_with_null_elements:[` +
+				fmt.Sprintf("%p", pointer) + ` <nil> <nil> 2] error:user not found data:{i am a good student 10}"}`,
+		},
+		{
+			desc:     "object",
+			config:   KafkaConfig{Merge: KafkaMergeObject, Promote: []string{"name"}},
+			fields:   append([]zapcore.Field{zap.String("services", "app")}, fields...),
+			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","services":"app","name":"user","data":{"age":12,"cash":1000.12,"null_value":null,"create_time":"2018-06-19T16:33:42Z","array_with_null_elements":[{},null,null,2],"error":"user not found","data":{}}}`,
+		},
+		{
+			desc:     "truncate",
+			config:   KafkaConfig{Merge: KafkaMergeObject, Promote: []string{"name"}, MaxMessageSize: 140},
+			fields:   []zapcore.Field{zap.String("name", "user"), zap.String("text", strings.Repeat("é", 50))},
+			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","name":"user","data":{"text":"éééééé"},"truncated":211}`,
+		},
+		{
+			desc:     "truncate string",
+			config:   KafkaConfig{MergeData: true, MaxMessageSize: 120},
+			fields:   []zapcore.Field{zap.String("text", strings.Repeat("é", 50))},
+			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","data":"text:ééééé","truncated":193}`,
+		},
+		{
+			desc:     "truncate dropping",
+			config:   KafkaConfig{Merge: KafkaMergeObject, MaxMessageSize: 140},
+			fields:   []zapcore.Field{zap.Ints("ids", make([]int, 50)), zap.String("user", "bob")},
+			expected: `{"level":"INFO","time":"2018-06-19T16:33:42Z","logger":"bob","msg":"lob law","data":{"user":"bob"},"truncated":208}`,
+		},
+		{
+			desc:   "drop",
+			config: KafkaConfig{MaxMessageSize: 120, Truncate: KafkaTruncateDrop},
+			fields: []zapcore.Field{zap.String("text", strings.Repeat("é", 50))},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
-			value, err := h.encode(tt.data)
+			tt.config.CoreConfig = CoreConfig{QueueLength: 10, Level: "info"}
+			h, err := newKafkaCore(&tt.config, "", map[string]string{"services": "app"}, encoderConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, err := h.encode(&CoreData{entry: entry, fields: tt.fields})
+			if tt.expected == "" {
+				if err == nil {
+					t.Fatalf("expected too large, get:%s", value)
+				}
+				return
+			}
 			if err != nil {
 				t.Fatal(err)
 			}
-			tt.expected += string(value[len(value)-1])
+			tt.expected += "\n"
 			if value != tt.expected {
-				t.Fatalf("\nexpected:%s get:%s\n", value, tt.expected)
+				t.Fatalf("\nexpected:%s get:%s\n", tt.expected, value)
 			}
-			//h.write(value)
 		})
 	}
 }
+
+func TestKafkaSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &KafkaConfig{
+		Topic:        "app-log",
+		PartitionKey: "user",
+		SpoolDir:     dir,
+		CoreConfig:   CoreConfig{QueueLength: 10, Level: "info"},
+	}
+	h, err := newKafkaCore(config, "", nil, zapcore.EncoderConfig{MessageKey: "msg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	producer := mocks.NewAsyncProducer(t, h.sarama)
+	h.start(producer)
+
+	// kafka is unreachable, the failed entry and the next ones are spooled
+	producer.ExpectInputAndFail(sarama.ErrOutOfBrokers)
+	h.write([]byte("a"), []byte("1"))
+	for i := 0; h.Stats().Spooled != 1; i++ {
+		if i > 100 {
+			t.Fatal("entry not spooled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	h.write([]byte("b"), []byte("2"))
+	h.write(nil, []byte("3"))
+	if h.spool.Len() != 3 {
+		t.Fatalf("expected 3 spooled, got %d", h.spool.Len())
+	}
+
+	// kafka recovers, the spool is replayed in order
+	var got []string
+	for i := 0; i < 3; i++ {
+		producer.ExpectInputWithCheckerFunctionAndSucceed(func(value []byte) error {
+			got = append(got, string(value))
+			return nil
+		})
+	}
+	if err := h.replay(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "1,2,3" {
+		t.Fatalf("unexpected replay order %v", got)
+	}
+	if h.spool.Active() {
+		t.Fatal("expected spool inactive after replay")
+	}
+
+	producer.ExpectInputAndSucceed()
+	h.writeData(&CoreData{
+		entry:  zapcore.Entry{Message: "direct"},
+		fields: []zapcore.Field{zap.String("user", "bob")},
+	})
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := h.Stats()
+	if stats.Sent != 4 || stats.Replayed != 3 || stats.Spooled != 3 || stats.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestKafkaSpoolReplayWrites(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &KafkaConfig{
+		Topic:      "app-log",
+		SpoolDir:   dir,
+		CoreConfig: CoreConfig{QueueLength: 10, Level: "info"},
+	}
+	h, err := newKafkaCore(config, "", nil, zapcore.EncoderConfig{MessageKey: "msg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 200; i++ {
+		h.spool.append(nil, []byte(fmt.Sprintf("spooled-%d", i)))
+	}
+	producer := mocks.NewAsyncProducer(t, h.sarama)
+	h.start(producer)
+
+	// entries written during the replay are spooled after the replayed ones
+	var got []string
+	for i := 0; i < 250; i++ {
+		producer.ExpectInputWithCheckerFunctionAndSucceed(func(value []byte) error {
+			got = append(got, string(value))
+			if string(value) == "spooled-10" {
+				for i := 0; i < 50; i++ {
+					h.write(nil, []byte(fmt.Sprintf("live-%d", i)))
+				}
+			}
+			return nil
+		})
+	}
+	if err := h.replay(); err != nil {
+		t.Fatal(err)
+	}
+	if h.spool.Active() || h.spool.Len() != 0 {
+		t.Fatalf("expected spool replayed, left %d", h.spool.Len())
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, value := range got {
+		expected := fmt.Sprintf("spooled-%d", i)
+		if i >= 200 {
+			expected = fmt.Sprintf("live-%d", i-200)
+		}
+		if value != expected {
+			t.Fatalf("expected %s at %d get:%s", expected, i, value)
+		}
+	}
+	if stats := h.Stats(); stats.Replayed != 250 || stats.Spooled != 50 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSpoolReopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := openSpool(dir, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.append([]byte("k"), []byte("first"))
+	s.append(nil, []byte("second"))
+	s.close()
+
+	// a partial record left by a crash is dropped
+	f, _ := os.OpenFile(filepath.Join(dir, "test.spool"), os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{0, 0, 0, 9, 'p'})
+	f.Close()
+
+	s, err = openSpool(dir, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.close()
+	if !s.Active() || s.Len() != 2 {
+		t.Fatalf("expected 2 records, got %d", s.Len())
+	}
+
+	var got []string
+	s.replay(1, func(key, value []byte) func() error {
+		return func() error {
+			if string(value) == "second" {
+				return errors.New("unreachable")
+			}
+			got = append(got, string(key)+"="+string(value))
+			return nil
+		}
+	})
+	if len(got) != 1 || got[0] != "k=first" || s.Len() != 1 {
+		t.Fatalf("unexpected replay %v, left %d", got, s.Len())
+	}
+}
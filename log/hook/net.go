@@ -0,0 +1,227 @@
+package hook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Net defaults
+var (
+	DefaultNetTimeout    = 3 * time.Second
+	DefaultNetBackoff    = 500 * time.Millisecond
+	DefaultNetMaxBackoff = time.Minute
+)
+
+// Networks
+const (
+	NetworkTCP      = "tcp"
+	NetworkTLS      = "tls"
+	NetworkUDP      = "udp"
+	NetworkUnix     = "unix"
+	NetworkUnixgram = "unixgram"
+)
+
+var errNotConnected = errors.New("not connected")
+
+// NetConfig is the connection of a network sink, dialed on the first entry
+// and redialed after a failure.
+type NetConfig struct {
+	Network string // tcp/tls/udp/unix/unixgram
+	Addr    string // host:port or socket path
+	Timeout int    // ms of a dial or write, default 3000
+	Backoff int    // ms before redialing, doubled up to 1 min, default 500
+	TLS     *TLSConfig
+}
+
+// TLSConfig of the tls network, the system roots are used if CAFile is
+// empty.
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// NetStats counters of a network sink.
+type NetStats struct {
+	Sent    uint64 // entries written
+	Failed  uint64 // entries dropped because of the connection
+	Redials uint64 // connections dialed after the first
+}
+
+// netConn writes to a connection, redialing it after a failure.
+type netConn struct {
+	name    string
+	network string
+	addr    string
+	tls     *tls.Config
+	timeout time.Duration
+	backoff time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	dialed bool
+	next   time.Time     // no dial before
+	delay  time.Duration // before the next dial after a failure
+
+	sent    uint64
+	failed  uint64
+	redials uint64
+}
+
+func newNetConn(name string, config *NetConfig) (*netConn, error) {
+	c := &netConn{
+		name:    name,
+		network: config.Network,
+		addr:    config.Addr,
+		timeout: DefaultNetTimeout,
+		backoff: DefaultNetBackoff,
+	}
+	if config.Timeout > 0 {
+		c.timeout = time.Duration(config.Timeout) * time.Millisecond
+	}
+	if config.Backoff > 0 {
+		c.backoff = time.Duration(config.Backoff) * time.Millisecond
+	}
+
+	switch c.network {
+	case NetworkTCP, NetworkUDP, NetworkUnix, NetworkUnixgram:
+	case NetworkTLS:
+		var err error
+		if c.tls, err = newTLSConfig(config.TLS); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown network %q", name, c.network)
+	}
+	if c.addr == "" {
+		return nil, fmt.Errorf("%s: no address", name)
+	}
+	return c, nil
+}
+
+func newTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if config == nil {
+		return cfg, nil
+	}
+
+	cfg.ServerName = config.ServerName
+	cfg.InsecureSkipVerify = config.InsecureSkipVerify
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %s", config.CAFile)
+		}
+	}
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// stream reports whether the connection keeps the boundaries of the
+// messages itself.
+func (c *netConn) stream() bool {
+	return c.network != NetworkUDP && c.network != NetworkUnixgram
+}
+
+func (c *netConn) dial() error {
+	if now := time.Now(); now.Before(c.next) {
+		return errNotConnected
+	}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	dialer := &net.Dialer{Timeout: c.timeout}
+	if c.network == NetworkTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, c.tls)
+	} else {
+		conn, err = dialer.Dial(c.network, c.addr)
+	}
+	if err != nil {
+		if c.delay == 0 {
+			c.delay = c.backoff
+		}
+		c.next = time.Now().Add(c.delay)
+		if c.delay *= 2; c.delay > DefaultNetMaxBackoff {
+			c.delay = DefaultNetMaxBackoff
+		}
+		return err
+	}
+
+	if c.dialed {
+		atomic.AddUint64(&c.redials, 1)
+	}
+	c.conn, c.dialed, c.delay = conn, true, 0
+	return nil
+}
+
+// write writes p, redialing once if a stream connection is broken.
+func (c *netConn) write(p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for retry := 0; retry < 2; retry++ {
+		if c.conn == nil {
+			if err = c.dial(); err != nil {
+				break
+			}
+		}
+		c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+		if _, err = c.conn.Write(p); err == nil {
+			atomic.AddUint64(&c.sent, 1)
+			return
+		}
+		c.conn.Close()
+		c.conn = nil
+		if !c.stream() {
+			break
+		}
+	}
+
+	// report the first and every 1000th failure
+	if n := atomic.AddUint64(&c.failed, 1); n%1000 == 1 {
+		fmt.Fprintf(os.Stderr, "[log] %s write %s %s err: %v, %d entries dropped\n",
+			c.name, c.network, c.addr, err, n)
+	}
+}
+
+func (c *netConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *netConn) stats() NetStats {
+	return NetStats{
+		Sent:    atomic.LoadUint64(&c.sent),
+		Failed:  atomic.LoadUint64(&c.failed),
+		Redials: atomic.LoadUint64(&c.redials),
+	}
+}
@@ -0,0 +1,196 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSyslog(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	h, err := NewSyslogCore(&SyslogConfig{
+		NetConfig: NetConfig{Network: NetworkUDP, Addr: pc.LocalAddr().String()},
+		AppName:   "app",
+		Hostname:  "web-1",
+		Facility:  "local0",
+	}, zapcore.EncoderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	ent := zapcore.Entry{
+		Level:      zapcore.ErrorLevel,
+		Time:       time.Date(2018, 6, 19, 16, 33, 42, 0, time.UTC),
+		LoggerName: "bob",
+		Message:    "hi",
+	}
+	h.writeData(&CoreData{entry: ent, fields: []zapcore.Field{
+		zap.Int("n", 1), zap.String("a", `x"y]`),
+	}})
+
+	buf := make([]byte, 1024)
+	pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<131>1 2018-06-19T16:33:42.000000Z web-1 app ` + strconv.Itoa(os.Getpid()) +
+		` bob [fields@32473 a="x\"y\]" n="1"] hi`
+	if string(buf[:n]) != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, buf[:n])
+	}
+
+	// octet counting over streams
+	h.config.Network = NetworkTCP
+	h.conn.network = NetworkTCP
+	msg, err := h.message(&CoreData{entry: ent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := `<131>1 2018-06-19T16:33:42.000000Z web-1 app ` + strconv.Itoa(os.Getpid()) + ` bob - hi`
+	if string(msg) != strconv.Itoa(len(body))+" "+body {
+		t.Errorf("framed %q", msg)
+	}
+
+	h.config.Protocol = SyslogRFC3164
+	h.config.Framing = FramingNewline
+	msg, _ = h.message(&CoreData{entry: ent})
+	if expected := "<131>Jun 19 16:33:42 web-1 app[" + strconv.Itoa(os.Getpid()) + "]: hi\n"; string(msg) != expected {
+		t.Errorf("rfc3164 %q", msg)
+	}
+
+	if _, err = NewSyslogCore(&SyslogConfig{Facility: "nope"}, zapcore.EncoderConfig{}); err == nil {
+		t.Error("unknown facility")
+	}
+}
+
+func TestSocketReconnect(t *testing.T) {
+	// the server is down at first
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h, err := NewSocketCore(&SocketConfig{
+		NetConfig: NetConfig{Addr: addr, Backoff: 1, Timeout: 500},
+	}, zapcore.EncoderConfig{MessageKey: "msg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	h.writeData(&CoreData{entry: zapcore.Entry{Message: "lost"}})
+	if stats := h.Stats(); stats.Failed != 1 || stats.Sent != 0 {
+		t.Fatalf("stats %+v", stats)
+	}
+
+	lines := make(chan string, 100)
+	serve := func() (stop func()) {
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns := make(chan net.Conn, 1)
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				close(conns)
+				return
+			}
+			conns <- conn
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+		return func() {
+			ln.Close()
+			if conn, ok := <-conns; ok {
+				conn.Close()
+			}
+		}
+	}
+
+	// written once the server is up
+	stop := serve()
+	time.Sleep(5 * time.Millisecond)
+	h.writeData(&CoreData{entry: zapcore.Entry{Message: "first"}})
+	select {
+	case line := <-lines:
+		if line != `{"msg":"first"}` {
+			t.Errorf("line %s", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("first not received")
+	}
+
+	// the server restarts, the broken connection is redialed
+	stop()
+	stop = serve()
+	defer stop()
+
+	deadline := time.After(3 * time.Second)
+	for {
+		h.writeData(&CoreData{entry: zapcore.Entry{Message: "again"}})
+		select {
+		case line := <-lines:
+			if line != `{"msg":"again"}` {
+				t.Errorf("line %s", line)
+			}
+			if stats := h.Stats(); stats.Redials == 0 {
+				t.Errorf("stats %+v", stats)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("not redialed, stats %+v", h.Stats())
+		}
+	}
+}
+
+func TestFluent(t *testing.T) {
+	h, err := NewFluentCore(&FluentConfig{
+		NetConfig:   NetConfig{Addr: "127.0.0.1:24224"},
+		Tag:         "app",
+		IntegerTime: true,
+	}, zapcore.EncoderConfig{MessageKey: "msg", LevelKey: "level"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	event := h.event(&CoreData{
+		entry:  zapcore.Entry{Time: time.Unix(1, 0), Message: "hi"},
+		fields: []zapcore.Field{zap.Int("n", -1), zap.Int("big", 300)},
+	})
+	expected := []byte{0x93, 0xa3, 'a', 'p', 'p', 0x01, 0x84,
+		0xa3, 'b', 'i', 'g', 0xcd, 0x01, 0x2c,
+		0xa5, 'l', 'e', 'v', 'e', 'l', 0xa4, 'i', 'n', 'f', 'o',
+		0xa3, 'm', 's', 'g', 0xa2, 'h', 'i',
+		0xa1, 'n', 0xff,
+	}
+	if !bytes.Equal(event, expected) {
+		t.Errorf("\nexpected:% x\n     get:% x", expected, event)
+	}
+
+	h.config.IntegerTime = false
+	event = h.event(&CoreData{entry: zapcore.Entry{Time: time.Unix(1, 5)}})
+	if !bytes.Equal(event[5:15], []byte{0xd7, 0x00, 0, 0, 0, 1, 0, 0, 0, 5}) {
+		t.Errorf("event time % x", event[5:15])
+	}
+}
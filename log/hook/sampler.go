@@ -0,0 +1,118 @@
+package hook
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	_minLevel         = zapcore.DebugLevel
+	_maxLevel         = zapcore.FatalLevel
+	_numLevels        = _maxLevel - _minLevel + 1
+	_countersPerLevel = 1024
+)
+
+// SamplingConfig samples and limits the entries of a core. Per second and
+// message the first Initial entries are written then every Thereafter-th,
+// Rate then limits the entries per second of each level.
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int
+	Rate       map[string]int // entries per second of a level, unlimited if 0
+	Burst      map[string]int // entries written at once of a level, default its Rate
+}
+
+// Sampler drops the entries over a SamplingConfig, counting them per level.
+type Sampler struct {
+	initial    uint64
+	thereafter uint64
+	counts     [_numLevels][_countersPerLevel]counter
+	limiters   [_numLevels]*limiter
+	dropped    [_numLevels]uint64
+}
+
+type counter struct {
+	resetAt int64
+	count   uint64
+}
+
+// inc increments the counter of the second of t, like zapcore.NewSampler.
+func (c *counter) inc(t time.Time) uint64 {
+	tn := t.UnixNano()
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > tn {
+		return atomic.AddUint64(&c.count, 1)
+	}
+
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, tn+int64(time.Second)) {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+// NewSampler returns nil if config neither samples nor limits.
+func NewSampler(config *SamplingConfig) *Sampler {
+	if config == nil || (config.Initial <= 0 && len(config.Rate) == 0) {
+		return nil
+	}
+
+	s := &Sampler{}
+	if config.Initial > 0 {
+		s.initial = uint64(config.Initial)
+		s.thereafter = uint64(config.Thereafter)
+	}
+	for name, rate := range config.Rate {
+		if rate <= 0 {
+			continue
+		}
+		lvl := ParseLevel(name)
+		if lvl < _minLevel || lvl > _maxLevel {
+			continue
+		}
+		burst, ok := config.Burst[name]
+		if !ok || burst <= 0 {
+			burst = rate
+		}
+		s.limiters[lvl-_minLevel] = newLimiter(float64(rate), burst)
+	}
+	return s
+}
+
+// Allow reports whether ent is written, counting it as dropped otherwise.
+func (s *Sampler) Allow(ent zapcore.Entry) bool {
+	if ent.Level < _minLevel || ent.Level > _maxLevel {
+		return true
+	}
+	i := ent.Level - _minLevel
+
+	if s.initial > 0 {
+		h := fnv.New32a()
+		h.Write([]byte(ent.Message))
+		n := s.counts[i][h.Sum32()%_countersPerLevel].inc(ent.Time)
+		if n > s.initial && (s.thereafter == 0 || (n-s.initial)%s.thereafter != 0) {
+			atomic.AddUint64(&s.dropped[i], 1)
+			return false
+		}
+	}
+
+	if l := s.limiters[i]; l != nil && !l.allow() {
+		atomic.AddUint64(&s.dropped[i], 1)
+		return false
+	}
+	return true
+}
+
+// Drops returns the entries dropped per level since the last call.
+func (s *Sampler) Drops() map[zapcore.Level]uint64 {
+	drops := make(map[zapcore.Level]uint64)
+	for i := range s.dropped {
+		if n := atomic.SwapUint64(&s.dropped[i], 0); n != 0 {
+			drops[_minLevel+zapcore.Level(i)] = n
+		}
+	}
+	return drops
+}
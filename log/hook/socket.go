@@ -0,0 +1,105 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SocketConfig writes the entries encoded in Format, one per line, to a
+// tcp/tls/unix stream or one per datagram to udp/unixgram, e.g. to Logstash
+// or Vector.
+type SocketConfig struct {
+	CoreConfig
+	NetConfig
+}
+
+// SocketCore ..
+type SocketCore struct {
+	*BaseCore
+
+	config *SocketConfig
+	conn   *netConn
+}
+
+// NewSocketCore ...
+func NewSocketCore(config *SocketConfig, encode zapcore.EncoderConfig) (core *SocketCore, err error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(config.Format, encode)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Network == "" {
+		config.Network = NetworkTCP
+	}
+	conn, err := newNetConn(config.Name, &config.NetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	core = &SocketCore{
+		BaseCore: &BaseCore{
+			queue:       make(chan *CoreData, config.QueueLength),
+			AtomicLevel: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
+			name:        config.Name,
+			enc:         enc,
+			out:         zapcore.AddSync(ioutil.Discard),
+			filters:     getfilters(config.Filter),
+			fields:      CombineFields(config.Fields, nil),
+			off:         config.Off,
+			dedup:       newDedup(config.Dedup),
+			sampler:     NewSampler(config.Sampling),
+			redactor:    redactor,
+		},
+		config: config,
+		conn:   conn,
+	}
+	core.BaseCore.core = core
+	core.start()
+	return core, nil
+}
+
+func (c *SocketCore) writeData(data *CoreData) {
+	buf, err := c.enc.EncodeEntry(data.entry, data.fields)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[log] %s encode err: %v\n", c.name, err)
+		return
+	}
+	defer buf.Free()
+
+	line := buf.Bytes()
+	if n := len(line); n == 0 || line[n-1] != '\n' {
+		buf.AppendByte('\n')
+		line = buf.Bytes()
+	}
+	c.conn.write(line)
+}
+
+// Stats returns the counters of the core.
+func (c *SocketCore) Stats() NetStats {
+	return c.conn.stats()
+}
+
+// Shutdown stops accepting entries, writes the queued ones and closes the
+// connection.
+func (c *SocketCore) Shutdown(ctx context.Context) error {
+	err := c.BaseCore.Shutdown(ctx)
+	if cerr := c.conn.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close ..
+func (c *SocketCore) Close() error {
+	return closeCore(c)
+}
@@ -0,0 +1,228 @@
+package hook
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var errSpoolFull = errors.New("spool is full")
+
+// spool is an append only file buffering records while the sink is
+// unreachable. A record is the key and value, each prefixed by its
+// big endian uint32 length. Once active, every new record goes to the
+// spool until it has been replayed completely, so order is kept.
+type spool struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	size    int64
+	maxSize int64
+	count   int64
+	active  bool
+}
+
+func openSpool(dir, name string, maxSize int64) (*spool, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	s := &spool{
+		path:    filepath.Join(dir, name+".spool"),
+		maxSize: maxSize,
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s.file = file
+
+	// count the records left by a previous process, dropping a partial
+	// record left by a crash while appending
+	err = readRecords(file, 0, func(key, value []byte) error {
+		s.count++
+		s.size += int64(8 + len(key) + len(value))
+		return nil
+	})
+	if err == io.ErrUnexpectedEOF {
+		err = file.Truncate(s.size)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	s.active = s.count > 0
+	return s, nil
+}
+
+// Active reports whether records must be appended to the spool.
+func (s *spool) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Len returns the number of spooled records.
+func (s *spool) Len() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+func (s *spool) append(key, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(8 + len(key) + len(value))
+	if s.maxSize > 0 && s.size+n > s.maxSize {
+		return errSpoolFull
+	}
+
+	buf := make([]byte, 0, n)
+	buf = appendChunk(buf, key)
+	buf = appendChunk(buf, value)
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	s.size += n
+	s.count++
+	s.active = true
+	return nil
+}
+
+// replay sends the records in order with up to window of them waiting
+// for the result returned by wait, stopping at the first error. The records
+// up to the first failed one are removed, the ones sent after it may be
+// sent again. The spool is deactivated once empty.
+func (s *spool) replay(window int, send func(key, value []byte) (wait func() error)) error {
+	s.mu.Lock()
+	end := s.size
+	s.mu.Unlock()
+
+	type sent struct {
+		wait func() error
+		size int64
+	}
+	var (
+		offset   int64
+		replayed int64
+		pending  []sent
+	)
+	ack := func() error {
+		p := pending[0]
+		pending = pending[1:]
+		if err := p.wait(); err != nil {
+			return err
+		}
+		offset += p.size
+		replayed++
+		return nil
+	}
+	err := readRecords(io.NewSectionReader(s.file, 0, end), 0, func(key, value []byte) error {
+		if len(pending) >= window {
+			if err := ack(); err != nil {
+				return err
+			}
+		}
+		pending = append(pending, sent{send(key, value), int64(8 + len(key) + len(value))})
+		return nil
+	})
+	for err == nil && len(pending) > 0 {
+		err = ack()
+	}
+	if offset == 0 {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if terr := s.truncate(offset); terr != nil {
+		return terr
+	}
+	s.count -= replayed
+	s.active = s.count > 0
+	return err
+}
+
+// truncate drops the first n bytes of the file.
+func (s *spool) truncate(n int64) error {
+	rest := io.NewSectionReader(s.file, n, s.size-n)
+	tmp, err := os.Create(s.path + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, rest); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(s.path+".tmp", s.path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = file
+	s.size -= n
+	return nil
+}
+
+func (s *spool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+func appendChunk(buf, chunk []byte) []byte {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(chunk)))
+	buf = append(buf, l[:]...)
+	return append(buf, chunk...)
+}
+
+func readRecords(r io.ReaderAt, offset int64, fn func(key, value []byte) error) error {
+	br := bufio.NewReader(io.NewSectionReader(r, offset, 1<<62))
+	for {
+		key, err := readChunk(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		value, err := readChunk(br)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+func readChunk(r io.Reader) ([]byte, error) {
+	var l [4]byte
+	if _, err := io.ReadFull(r, l[:]); err != nil {
+		return nil, err
+	}
+	chunk := make([]byte, binary.BigEndian.Uint32(l[:]))
+	if _, err := io.ReadFull(r, chunk); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return chunk, nil
+}
@@ -0,0 +1,284 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Syslog protocols
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164" // BSD syslog, for the older syslogd and journald
+)
+
+// Syslog framings of the stream networks, RFC 6587.
+const (
+	FramingOctet   = "octet"   // MSG-LEN SP SYSLOG-MSG
+	FramingNewline = "newline" // SYSLOG-MSG LF
+)
+
+// DefaultSyslogSDID is the SD-ID of the structured data holding the fields.
+var DefaultSyslogSDID = "fields@32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities of the levels.
+var syslogSeverities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  1,
+	zapcore.FatalLevel:  0,
+}
+
+// SyslogConfig writes the entries to a syslog server, or to the local
+// syslogd and journald with unixgram /dev/log. The fields are written as
+// structured data, unless Format is set to write the encoded entries as
+// the message.
+type SyslogConfig struct {
+	CoreConfig
+	NetConfig
+	Protocol string // rfc5424/rfc3164, default rfc5424
+	Framing  string // of tcp/tls/unix: octet/newline, default octet
+	Facility string // default user
+	AppName  string // default the program name
+	Hostname string // default os.Hostname
+}
+
+// SyslogCore ..
+type SyslogCore struct {
+	*BaseCore
+
+	config   *SyslogConfig
+	conn     *netConn
+	priority int // facility * 8
+	hostname string
+	appName  string
+	pid      string
+}
+
+// NewSyslogCore ...
+func NewSyslogCore(config *SyslogConfig, encode zapcore.EncoderConfig) (core *SyslogCore, err error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(config.Format, encode)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Network == "" {
+		config.Network = NetworkUDP
+	}
+	if config.Addr == "" && (config.Network == NetworkUnix || config.Network == NetworkUnixgram) {
+		config.Addr = "/dev/log"
+	}
+	conn, err := newNetConn(config.Name, &config.NetConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	switch config.Protocol {
+	case "":
+		config.Protocol = SyslogRFC5424
+	case SyslogRFC5424, SyslogRFC3164:
+	default:
+		return nil, fmt.Errorf("%s: unknown protocol %q", config.Name, config.Protocol)
+	}
+	switch config.Framing {
+	case "":
+		config.Framing = FramingOctet
+	case FramingOctet, FramingNewline:
+	default:
+		return nil, fmt.Errorf("%s: unknown framing %q", config.Name, config.Framing)
+	}
+	facility, ok := syslogFacilities[strings.ToLower(config.Facility)]
+	if config.Facility == "" {
+		facility, ok = syslogFacilities["user"], true
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown facility %q", config.Name, config.Facility)
+	}
+
+	core = &SyslogCore{
+		BaseCore: &BaseCore{
+			queue:       make(chan *CoreData, config.QueueLength),
+			AtomicLevel: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
+			name:        config.Name,
+			enc:         enc,
+			out:         zapcore.AddSync(ioutil.Discard),
+			filters:     getfilters(config.Filter),
+			fields:      CombineFields(config.Fields, nil),
+			off:         config.Off,
+			dedup:       newDedup(config.Dedup),
+			sampler:     NewSampler(config.Sampling),
+			redactor:    redactor,
+		},
+		config:   config,
+		conn:     conn,
+		priority: facility * 8,
+		hostname: config.Hostname,
+		appName:  config.AppName,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	core.BaseCore.core = core
+
+	if core.hostname == "" {
+		core.hostname, _ = os.Hostname()
+	}
+	if core.hostname == "" {
+		core.hostname = "-"
+	}
+	if core.appName == "" {
+		core.appName = filepath.Base(os.Args[0])
+	}
+
+	core.start()
+	return core, nil
+}
+
+// message returns the syslog message of data, framed for the network.
+func (c *SyslogCore) message(data *CoreData) ([]byte, error) {
+	severity, ok := syslogSeverities[data.entry.Level]
+	if !ok {
+		severity = 6
+	}
+
+	msg := data.entry.Message
+	if data.entry.Stack != "" {
+		msg += "\n" + data.entry.Stack
+	}
+	if c.config.Format != "" {
+		buf, err := c.enc.EncodeEntry(data.entry, data.fields)
+		if err != nil {
+			return nil, err
+		}
+		msg = strings.TrimSuffix(buf.String(), "\n")
+		buf.Free()
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>", c.priority+severity)
+	if c.config.Protocol == SyslogRFC3164 {
+		// the fields are lost without a Format
+		fmt.Fprintf(&b, "%s %s %s[%s]: %s", data.entry.Time.Format(time.Stamp),
+			c.hostname, c.appName, c.pid, msg)
+	} else {
+		msgID := "-"
+		if data.entry.LoggerName != "" {
+			msgID = syslogName(data.entry.LoggerName, 32)
+		}
+		fmt.Fprintf(&b, "1 %s %s %s %s %s ",
+			data.entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+			syslogName(c.hostname, 255), syslogName(c.appName, 48), c.pid, msgID)
+		if c.config.Format == "" {
+			c.structuredData(&b, data.fields)
+		} else {
+			b.WriteByte('-')
+		}
+		b.WriteByte(' ')
+		b.WriteString(msg)
+	}
+
+	if !c.conn.stream() {
+		return b.Bytes(), nil
+	}
+	if c.config.Framing == FramingNewline {
+		b.WriteByte('\n')
+		return b.Bytes(), nil
+	}
+	return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...), nil
+}
+
+// structuredData writes the fields as an SD-ELEMENT, sorted by key.
+func (c *SyslogCore) structuredData(b *bytes.Buffer, fields []zapcore.Field) {
+	if len(fields) == 0 {
+		b.WriteByte('-')
+		return
+	}
+
+	sorted := make([]zapcore.Field, len(fields))
+	copy(sorted, fields)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	b.WriteString("[" + DefaultSyslogSDID)
+	for _, f := range sorted {
+		b.WriteString(" " + syslogName(f.Key, 32) + `="`)
+		v := c.getFieldString(f)
+		for i := 0; i < len(v); i++ {
+			if ch := v[i]; ch == '"' || ch == '\\' || ch == ']' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(v[i])
+		}
+		b.WriteByte('"')
+	}
+	b.WriteByte(']')
+}
+
+// syslogName returns s with the characters out of PRINTUSASCII, and = ] "
+// the SD-NAMEs exclude, replaced by _, truncated to max.
+func syslogName(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	if len(s) > max {
+		s = s[:max]
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+func (c *SyslogCore) writeData(data *CoreData) {
+	msg, err := c.message(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[log] %s encode err: %v\n", c.name, err)
+		return
+	}
+	c.conn.write(msg)
+}
+
+// Stats returns the counters of the core.
+func (c *SyslogCore) Stats() NetStats {
+	return c.conn.stats()
+}
+
+// Shutdown stops accepting entries, writes the queued ones and closes the
+// connection.
+func (c *SyslogCore) Shutdown(ctx context.Context) error {
+	err := c.BaseCore.Shutdown(ctx)
+	if cerr := c.conn.close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Close ..
+func (c *SyslogCore) Close() error {
+	return closeCore(c)
+}
@@ -2,12 +2,23 @@ package hook
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+	"unicode/utf8"
 
+	"github.com/hkjojo/go-toolkits/log/redact"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -16,80 +27,462 @@ import (
 const (
 	MethodGET  = "GET"
 	MethodPOST = "POST"
+	MethodPUT  = "PUT"
+)
+
+// Webhook defaults
+var (
+	DefaultWebHookTimeout      = 5 * time.Second
+	DefaultWebHookRetryBackoff = 500 * time.Millisecond
+	DefaultWebHookMaxEntries   = 100
 )
 
 // WebHookConfig ..
+//
+// Message and KVMessage are text/template, Message is executed with a
+// WebHookData and KVMessage with each WebHookField of an entry. The legacy
+// placeholders {{content}}, {{key}} and {{value}} are still supported,
+// {{content}} is JSON escaped if ContentType is JSON.
 type WebHookConfig struct {
 	CoreConfig
 	Host        string
 	Message     string
 	KVMessage   string
-	Method      string
+	Method      string // GET/POST/PUT, default POST. GET requests Host+Message
 	ContentType string
+	Headers     map[string]string
+	SendConfig
+}
+
+// SendConfig configures how the messages of a webhook are sent.
+type SendConfig struct {
+	Timeout      int // request timeout：ms, default 5000
+	MaxRetries   int // retries of a failed request
+	RetryBackoff int // ms before the first retry, doubled each retry, default 500
+	Window       int // ms, entries of a window are sent in one message
+	MaxEntries   int // max entries of a window message, default 100
+	RateLimit    int // max messages per minute, unlimited if 0
+	RateBurst    int // messages sent at once under RateLimit, default RateLimit
+}
+
+// WebHookField is a field of an entry, as rendered by KVMessage.
+type WebHookField struct {
+	Key   string
+	Value interface{}
+}
+
+// WebHookEntry is an entry as rendered by Message.
+type WebHookEntry struct {
+	Level   string
+	Time    time.Time
+	Logger  string
+	Message string
+	Caller  string
+	Stack   string
+	Fields  []WebHookField // sorted by key
+	Content string         // fields rendered by KVMessage
+	Encoded string         // entry encoded in Format if set, e.g. a GELF message
+}
+
+// WebHookData is the data of Message, the first entry of a window with all
+// the entries sent in the message.
+type WebHookData struct {
+	*WebHookEntry
+	Entries []*WebHookEntry
+	Dropped int       // entries of the window over MaxEntries
+	Content string    // content of the entries
+	Now     time.Time // time the message is rendered
+}
+
+// WebHookStats counters of a WebHookCore.
+type WebHookStats struct {
+	Sent    uint64 // messages accepted by the host
+	Failed  uint64 // messages failed after the retries
+	Limited uint64 // messages dropped by the rate limit
+}
+
+// TemplateFuncs are the functions available to the webhook templates.
+var TemplateFuncs = template.FuncMap{
+	"json":       jsonString,
+	"jsonEscape": jsonEscape,
+	"markdown":   markdownEscape,
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"truncate":   truncate,
 }
 
 // WebHookCore ..
 type WebHookCore struct {
 	*BaseCore
 
-	config *WebHookConfig
+	config  *WebHookConfig
+	message *template.Template
+	kv      *template.Template
+	client  *http.Client
+	limiter *limiter
+	prepare func(req *http.Request) // adjusts each request, e.g. to sign it
+
+	mu      sync.Mutex // protects the window
+	pending []*WebHookEntry
+	dropped int
+	timer   *time.Timer
+	sendMu  sync.Mutex // serializes the messages
+
+	sent    uint64
+	failed  uint64
+	limited uint64
 }
 
-// NewWebHookCore ...
+// NewWebHookCore ... it panics on an invalid config, CreateWebHookCore
+// returns the error instead.
 func NewWebHookCore(config *WebHookConfig, encode zapcore.EncoderConfig) (core *WebHookCore) {
+	core, err := CreateWebHookCore(config, encode)
+	if err != nil {
+		panic(err)
+	}
+	return core
+}
+
+// CreateWebHookCore creates a webhook core, failing on an invalid format,
+// redaction or template.
+func CreateWebHookCore(config *WebHookConfig, encode zapcore.EncoderConfig) (core *WebHookCore, err error) {
+	return newWebHookCore(config, encode, nil, nil)
+}
+
+// newWebHookCore creates a webhook core whose templates may use funcs in
+// addition to TemplateFuncs.
+func newWebHookCore(config *WebHookConfig, encode zapcore.EncoderConfig,
+	funcs template.FuncMap, prepare func(req *http.Request)) (core *WebHookCore, err error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := newEncoder(config.Format, encode)
+	if err != nil {
+		return nil, err
+	}
+
 	core = &WebHookCore{
 		BaseCore: &BaseCore{
-			queue:        make(chan *CoreData, config.QueueLength),
-			LevelEnabler: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
-			enc:          zapcore.NewJSONEncoder(encode),
-			out:          zapcore.AddSync(ioutil.Discard),
-			filters:      getfilters(config.Filter),
-			fields:       CombineFields(config.Fields, config.Fields),
-			off:          config.Off,
+			queue:       make(chan *CoreData, config.QueueLength),
+			AtomicLevel: zap.NewAtomicLevelAt(ParseLevel(config.Level)),
+			name:        config.Name,
+			enc:         enc,
+			out:         zapcore.AddSync(ioutil.Discard),
+			filters:     getfilters(config.Filter),
+			fields:      CombineFields(config.Fields, config.Fields),
+			off:         config.Off,
+			dedup:       newDedup(config.Dedup),
+			sampler:     NewSampler(config.Sampling),
+			redactor:    redactor,
 		},
-		config: config,
+		config:  config,
+		client:  &http.Client{Timeout: DefaultWebHookTimeout},
+		prepare: prepare,
 	}
 	core.BaseCore.core = core
+
+	escape := strings.Contains(strings.ToLower(config.ContentType), "json")
+	core.message, err = template.New("message").Funcs(TemplateFuncs).Funcs(funcs).
+		Parse(legacyTemplate(config.Message, escape))
+	if err != nil {
+		return nil, err
+	}
+	core.kv, err = template.New("kv").Funcs(TemplateFuncs).
+		Parse(legacyTemplate(config.KVMessage, false))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Timeout > 0 {
+		core.client.Timeout = time.Duration(config.Timeout) * time.Millisecond
+	}
+	if config.RateLimit > 0 {
+		burst := config.RateBurst
+		if burst <= 0 {
+			burst = config.RateLimit
+		}
+		core.limiter = newLimiter(float64(config.RateLimit)/60, burst)
+	}
+
 	core.start()
-	return
+	return core, nil
 }
 
-func (c *WebHookCore) encode(data *CoreData) string {
-	var content string
-	msg := c.config.Message
-	mergeEntryFields(data.entry, data.fields)
+// legacyTemplate converts the {{content}}, {{key}} and {{value}}
+// placeholders to template actions.
+func legacyTemplate(text string, escape bool) string {
+	content := "{{.Content}}"
+	if escape {
+		content = "{{jsonEscape .Content}}"
+	}
+	return strings.NewReplacer(
+		"{{content}}", content,
+		"{{key}}", "{{.Key}}",
+		"{{value}}", "{{.Value}}",
+	).Replace(text)
+}
+
+func (c *WebHookCore) entry(data *CoreData) *WebHookEntry {
+	entry := &WebHookEntry{
+		Level:   data.entry.Level.CapitalString(),
+		Time:    data.entry.Time,
+		Logger:  data.entry.LoggerName,
+		Message: data.entry.Message,
+		Stack:   data.entry.Stack,
+	}
+	if data.entry.Caller.Defined {
+		entry.Caller = data.entry.Caller.TrimmedPath()
+	}
+
 	for _, f := range data.fields {
-		var kv = strings.Replace(c.config.KVMessage, "{{key}}", f.Key, -1)
-		kv = strings.Replace(kv, "{{value}}", c.getFieldString(f), -1)
-		content += kv
+		entry.Fields = append(entry.Fields, WebHookField{f.Key, c.getField(f)})
+	}
+	sort.Slice(entry.Fields, func(i, j int) bool {
+		return entry.Fields[i].Key < entry.Fields[j].Key
+	})
+
+	var buf bytes.Buffer
+	for _, f := range entry.Fields {
+		if err := c.kv.Execute(&buf, f); err != nil {
+			fmt.Fprintf(os.Stderr, "[log] web hook template err: %v\n", err)
+		}
 	}
+	entry.Content = buf.String()
 
-	return strings.Replace(msg, "{{content}}", content, -1)
+	if c.config.Format != "" {
+		encoded, err := c.enc.EncodeEntry(data.entry, data.fields)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[log] web hook encode err: %v\n", err)
+		} else {
+			entry.Encoded = strings.TrimSuffix(encoded.String(), "\n")
+			encoded.Free()
+		}
+	}
+	return entry
+}
+
+// render renders the message of entries.
+func (c *WebHookCore) render(entries []*WebHookEntry, dropped int) (string, error) {
+	data := &WebHookData{
+		WebHookEntry: entries[0],
+		Entries:      entries,
+		Dropped:      dropped,
+		Now:          time.Now(),
+	}
+	for _, e := range entries {
+		data.Content += e.Content
+	}
+
+	var buf bytes.Buffer
+	if err := c.message.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (c *WebHookCore) encode(data *CoreData) string {
+	content, err := c.render([]*WebHookEntry{c.entry(data)}, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[log] web hook template err: %v\n", err)
+	}
+	return content
 }
 
 func (c *WebHookCore) writeData(data *CoreData) {
-	c.write(c.encode(data))
+	entry := c.entry(data)
+	if c.config.Window <= 0 {
+		c.send([]*WebHookEntry{entry}, 0)
+		return
+	}
+
+	max := c.config.MaxEntries
+	if max <= 0 {
+		max = DefaultWebHookMaxEntries
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) < max {
+		c.pending = append(c.pending, entry)
+	} else {
+		c.dropped++
+	}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(time.Duration(c.config.Window)*time.Millisecond, c.flush)
+	}
+}
+
+// flush sends the entries of the window.
+func (c *WebHookCore) flush() {
+	c.mu.Lock()
+	entries, dropped := c.pending, c.dropped
+	c.pending, c.dropped = nil, 0
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+
+	if len(entries) != 0 {
+		c.send(entries, dropped)
+	}
+}
+
+func (c *WebHookCore) send(entries []*WebHookEntry, dropped int) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+
+	if c.limiter != nil && !c.limiter.allow() {
+		atomic.AddUint64(&c.limited, 1)
+		return
+	}
+
+	content, err := c.render(entries, dropped)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[log] web hook template err: %v\n", err)
+		return
+	}
+
+	backoff := DefaultWebHookRetryBackoff
+	if c.config.RetryBackoff > 0 {
+		backoff = time.Duration(c.config.RetryBackoff) * time.Millisecond
+	}
+	for retry := 0; ; retry++ {
+		err = c.write(content)
+		if err == nil {
+			atomic.AddUint64(&c.sent, 1)
+			return
+		}
+		if retry >= c.config.MaxRetries {
+			break
+		}
+
+		select {
+		case <-time.After(backoff << uint(retry)):
+		case <-c.state.ctx.Done():
+			retry = c.config.MaxRetries
+		}
+	}
+
+	atomic.AddUint64(&c.failed, 1)
+	fmt.Fprintf(os.Stderr, "[log] web hook fail host:%s err:%v content:%s\n",
+		c.config.Host, err, content)
 }
 
-func (c *WebHookCore) write(content string) {
+func (c *WebHookCore) write(content string) error {
 	var (
-		rsp *http.Response
-		err error
+		target = c.config.Host
+		body   io.Reader
 	)
-	switch c.config.Method {
+	method := strings.ToUpper(c.config.Method)
+	switch method {
 	case MethodGET:
-		//rsp, err = http.Get(config.Host + req)
-	case MethodPOST:
-		rsp, err = http.Post(c.config.Host, c.config.ContentType,
-			bytes.NewBuffer([]byte(content)))
+		// the message ends the query of the host, e.g. ...?text=
+		target += url.QueryEscape(content)
+	case MethodPUT:
+		body = strings.NewReader(content)
+	default:
+		method = MethodPOST
+		body = strings.NewReader(content)
 	}
 
+	req, err := http.NewRequest(method, target, body)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "web hook fail host:%s err:%v content:%s rsp:%v\n",
-			c.config.Host, err, content, rsp)
+		return err
+	}
+	if c.config.ContentType != "" && body != nil {
+		req.Header.Set("Content-Type", c.config.ContentType)
+	}
+	for k, v := range c.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if c.prepare != nil {
+		c.prepare(req)
+	}
+
+	// pending requests are canceled when a shutdown is aborted
+	rsp, err := c.client.Do(req.WithContext(c.state.ctx))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 512))
+		return fmt.Errorf("code:%d rsp:%s", rsp.StatusCode, msg)
+	}
+	io.Copy(ioutil.Discard, rsp.Body)
+	return nil
+}
+
+// Stats returns the counters of the core.
+func (c *WebHookCore) Stats() WebHookStats {
+	return WebHookStats{
+		Sent:    atomic.LoadUint64(&c.sent),
+		Failed:  atomic.LoadUint64(&c.failed),
+		Limited: atomic.LoadUint64(&c.limited),
+	}
+}
+
+// Shutdown stops accepting entries, sends the queued ones and the pending
+// window. It returns once sent or ctx is done.
+func (c *WebHookCore) Shutdown(ctx context.Context) error {
+	if err := c.BaseCore.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.flush()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		c.state.cancel()
+		return fmt.Errorf("%v, window not sent", ctx.Err())
+	}
+}
+
+// Close shuts the core down within DefaultCloseTimeout.
+func (c *WebHookCore) Close() error {
+	return closeCore(c)
+}
+
+func jsonString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// jsonEscape escapes s to be used inside a JSON string.
+func jsonEscape(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`,
+	")", `\)`, "~", `\~`, "`", "\\`", ">", `\>`, "#", `\#`, "+", `\+`,
+	"-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`,
+	"!", `\!`,
+)
+
+// markdownEscape escapes the Markdown(V2) special characters of s.
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+// truncate cuts s to at most n bytes, on a rune boundary.
+func truncate(n int, s string) string {
+	if len(s) <= n {
+		return s
 	}
-	if rsp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "web hook fail host:%s code:%d content:%s rsp:%v\n",
-			c.config.Host, rsp.StatusCode, content, rsp)
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
 	}
+	return s[:n] + "..."
 }
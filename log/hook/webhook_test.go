@@ -1,7 +1,15 @@
 package hook
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -89,3 +97,178 @@ func TestTelegram(t *testing.T) {
 		})
 	}
 }
+
+func TestWebHook(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		bodies   []string
+		requests int32
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request fails and is retried
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Method != MethodPUT || r.Header.Get("X-Token") != "secret" {
+			t.Errorf("unexpected request %s %v", r.Method, r.Header)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	h, err := CreateWebHookCore(&WebHookConfig{
+		Host:        srv.URL,
+		Method:      MethodPUT,
+		ContentType: "application/json",
+		Headers:     map[string]string{"X-Token": "secret"},
+		Message:     `{"text": "{{content}}", "count": {{len .Entries}}, "first": {{json .Message}}}`,
+		KVMessage:   "{{key}}={{value}}\n",
+		SendConfig:  SendConfig{MaxRetries: 2, RetryBackoff: 1, Window: 50},
+		CoreConfig:  CoreConfig{QueueLength: 10, Level: "info"},
+	}, zapcore.EncoderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.writeData(&CoreData{
+		entry:  zapcore.Entry{Message: `say "hi"`},
+		fields: []zapcore.Field{zap.String("quote", `a "b"`)},
+	})
+	h.writeData(&CoreData{
+		entry:  zapcore.Entry{Message: "second"},
+		fields: []zapcore.Field{zap.String("line", "x\ny")},
+	})
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 aggregated message, got %v", bodies)
+	}
+	var msg struct {
+		Text  string
+		Count int
+		First string
+	}
+	if err := json.Unmarshal([]byte(bodies[0]), &msg); err != nil {
+		t.Fatalf("invalid json %s: %v", bodies[0], err)
+	}
+	if msg.Text != "quote=a \"b\"\nline=x\ny\n" || msg.Count != 2 || msg.First != `say "hi"` {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	if stats := h.Stats(); stats.Sent != 1 || stats.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestWebHookGet(t *testing.T) {
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != MethodGET {
+			t.Errorf("unexpected method %s", r.Method)
+		}
+		text = r.URL.Query().Get("text")
+		if r.URL.Query().Get("token") != "t" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+	}))
+	defer srv.Close()
+
+	h, err := CreateWebHookCore(&WebHookConfig{
+		Host:       srv.URL + "?token=t&text=",
+		Method:     "get",
+		Message:    "{{.Message}}: {{content}}",
+		KVMessage:  "{{key}}={{value}}&",
+		CoreConfig: CoreConfig{QueueLength: 10, Level: "info"},
+	}, zapcore.EncoderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.writeData(&CoreData{
+		entry:  zapcore.Entry{Message: "a+b #1"},
+		fields: []zapcore.Field{zap.String("q", "x y")},
+	})
+	if err := h.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "a+b #1: q=x y&"; text != expected {
+		t.Fatalf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestWebHookRateLimit(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer srv.Close()
+
+	h, err := CreateWebHookCore(&WebHookConfig{
+		Host:       srv.URL,
+		Message:    "{{markdown .Message}}",
+		SendConfig: SendConfig{RateLimit: 2},
+		CoreConfig: CoreConfig{QueueLength: 10, Level: "info"},
+	}, zapcore.EncoderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		h.writeData(&CoreData{entry: zapcore.Entry{Message: "storm"}})
+	}
+	h.Close()
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+	if stats := h.Stats(); stats.Sent != 2 || stats.Limited != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if got := markdownEscape("a_b*c [d](e)."); got != `a\_b\*c \[d\]\(e\)\.` {
+		t.Fatalf("unexpected escape %s", got)
+	}
+	if got := truncate(4, "日本語"); got != "日..." {
+		t.Fatalf("unexpected truncate %s", got)
+	}
+}
+
+func TestWebHookFormat(t *testing.T) {
+	h, err := CreateWebHookCore(&WebHookConfig{
+		Message:    "{{.Encoded}}",
+		CoreConfig: CoreConfig{Format: "gelf"},
+	}, zapcore.EncoderConfig{MessageKey: "msg", NameKey: "logger"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := h.encode(&CoreData{
+		entry:  zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Unix(1, 0), Message: "hi"},
+		fields: []zapcore.Field{zap.Int("n", 1)},
+	})
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	expected := `{"version":"1.1","host":"` + host + `","short_message":"hi","timestamp":1.000,"level":4,"_n":1}`
+	if content != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, content)
+	}
+
+	if _, err = CreateWebHookCore(&WebHookConfig{CoreConfig: CoreConfig{Format: "xml"}},
+		zapcore.EncoderConfig{}); err == nil {
+		t.Error("xml format")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("NewWebHookCore xml format")
+			}
+		}()
+		NewWebHookCore(&WebHookConfig{CoreConfig: CoreConfig{Format: "xml"}}, zapcore.EncoderConfig{})
+	}()
+}
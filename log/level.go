@@ -0,0 +1,352 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelSetter is a core whose level can be adjusted at runtime,
+// implemented by the hook cores.
+type LevelSetter interface {
+	Name() string
+	Level() zapcore.Level
+	SetLevel(zapcore.Level)
+}
+
+// Levels holds the runtime adjustable levels of a Logger: the level of the
+// main core, per named logger overrides and the level of each hook core.
+type Levels struct {
+	level   zap.AtomicLevel
+	minName int32 // lowest level of the name overrides, noName if none
+
+	mu      sync.RWMutex
+	names   map[string]zap.AtomicLevel
+	hooks   map[string]LevelSetter
+	reverts map[string]*pendingRevert
+	modules map[string]bool // names set by Config.Modules
+}
+
+// noName is the minName of no override, above every level.
+const noName = int32(zapcore.FatalLevel + 1)
+
+func newLevels(lvl zapcore.Level) *Levels {
+	return &Levels{
+		level:   zap.NewAtomicLevelAt(lvl),
+		minName: noName,
+		names:   make(map[string]zap.AtomicLevel),
+		hooks:   make(map[string]LevelSetter),
+		reverts: make(map[string]*pendingRevert),
+	}
+}
+
+func (l *Levels) setHooks(hooks []hookCore) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = make(map[string]LevelSetter, len(hooks))
+	for _, h := range hooks {
+		l.hooks[h.Name()] = h
+	}
+}
+
+// Enabled implements zapcore.LevelEnabler, a level is enabled if the main
+// level or any named override enables it.
+func (l *Levels) Enabled(lvl zapcore.Level) bool {
+	if l.level.Enabled(lvl) {
+		return true
+	}
+	if int32(lvl) < atomic.LoadInt32(&l.minName) {
+		return false
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, level := range l.names {
+		if level.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
+
+// NameEnabled reports whether lvl is enabled for the logger name, the
+// override with the longest matching dotted prefix wins.
+func (l *Levels) NameEnabled(name string, lvl zapcore.Level) bool {
+	if name == "" {
+		return l.level.Enabled(lvl)
+	}
+
+	l.mu.RLock()
+	level, ok := l.lookup(name)
+	l.mu.RUnlock()
+	if ok {
+		return level.Enabled(lvl)
+	}
+	return l.level.Enabled(lvl)
+}
+
+// updateMinName updates minName with l.mu held.
+func (l *Levels) updateMinName() {
+	min := noName
+	for _, level := range l.names {
+		if lvl := int32(level.Level()); lvl < min {
+			min = lvl
+		}
+	}
+	atomic.StoreInt32(&l.minName, min)
+}
+
+func (l *Levels) lookup(name string) (zap.AtomicLevel, bool) {
+	for {
+		if level, ok := l.names[name]; ok {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return zap.AtomicLevel{}, false
+		}
+		name = name[:i]
+	}
+}
+
+// Level returns the level of the main core.
+func (l *Levels) Level() zapcore.Level {
+	return l.level.Level()
+}
+
+// SetLevel sets the level of the main core, when d is positive the level
+// before the first pending change is restored after d.
+func (l *Levels) SetLevel(lvl zapcore.Level, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prev := l.level.Level()
+	l.level.SetLevel(lvl)
+	l.revertAfter("", d, func() { l.level.SetLevel(prev) })
+}
+
+// SetNameLevel overrides the level of the named logger and its children,
+// when d is positive the override before the first pending change, if any,
+// is restored after d.
+func (l *Levels) SetNameLevel(name string, lvl zapcore.Level, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	revert := func() { delete(l.names, name) }
+	if level, ok := l.names[name]; ok {
+		prev := level.Level()
+		revert = func() { level.SetLevel(prev) }
+		level.SetLevel(lvl)
+	} else {
+		l.names[name] = zap.NewAtomicLevelAt(lvl)
+	}
+	l.updateMinName()
+	l.revertAfter("name:"+name, d, revert)
+}
+
+// ResetNameLevel removes the override of the named logger.
+func (l *Levels) ResetNameLevel(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.names, name)
+	l.updateMinName()
+	l.revertAfter("name:"+name, 0, nil)
+}
+
+// SetHookLevel sets the level of the named hook core, when d is positive
+// the level before the first pending change is restored after d.
+func (l *Levels) SetHookLevel(name string, lvl zapcore.Level, d time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	h, ok := l.hooks[name]
+	if !ok {
+		return fmt.Errorf("hook not found: %s", name)
+	}
+	prev := h.Level()
+	h.SetLevel(lvl)
+	l.revertAfter("hook:"+name, d, func() { h.SetLevel(prev) })
+	return nil
+}
+
+// pendingRevert restores the state of a key before its first timed change.
+type pendingRevert struct {
+	timer  *time.Timer
+	revert func()
+}
+
+// revertAfter schedules revert after d with l.mu held. A pending revert of
+// key is kept instead, so the state before the first timed change is
+// restored, and rescheduled. It is cancelled if d is not positive.
+func (l *Levels) revertAfter(key string, d time.Duration, revert func()) {
+	p, ok := l.reverts[key]
+	if ok {
+		p.timer.Stop()
+		delete(l.reverts, key)
+		revert = p.revert
+	}
+	if d <= 0 {
+		return
+	}
+
+	p = &pendingRevert{revert: revert}
+	p.timer = time.AfterFunc(d, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.reverts[key] != p {
+			return
+		}
+		delete(l.reverts, key)
+		p.revert()
+		l.updateMinName()
+	})
+	l.reverts[key] = p
+}
+
+// LevelState is the body of the level http handler.
+type LevelState struct {
+	Level string            `json:"level"`
+	Names map[string]string `json:"names"`
+	Hooks map[string]string `json:"hooks"`
+}
+
+// State returns a snapshot of every level.
+func (l *Levels) State() *LevelState {
+	state := &LevelState{
+		Level: l.level.String(),
+		Names: make(map[string]string),
+		Hooks: make(map[string]string),
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for name, level := range l.names {
+		state.Names[name] = level.String()
+	}
+	for name, h := range l.hooks {
+		state.Hooks[name] = h.Level().String()
+	}
+	return state
+}
+
+// HookNames returns the sorted names of the hook cores.
+func (l *Levels) HookNames() []string {
+	l.mu.RLock()
+	names := make([]string, 0, len(l.hooks))
+	for name := range l.hooks {
+		names = append(names, name)
+	}
+	l.mu.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// levelCore applies the per named logger levels to the main core.
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return c.levels.Enabled(lvl)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{c.Core.With(fields), c.levels}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.levels.NameEnabled(ent.LoggerName, ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// LevelRequest is the body of a PUT to the level http handler, Name and
+// Hook select what to adjust, the main core if both are empty. A Duration
+// like "10m" reverts the change when it elapses, a Name override with an
+// empty Level is removed.
+type LevelRequest struct {
+	Level    string `json:"level"`
+	Name     string `json:"name"`
+	Hook     string `json:"hook"`
+	Duration string `json:"duration"`
+}
+
+func (l *Levels) apply(req *LevelRequest) error {
+	var d time.Duration
+	if req.Duration != "" {
+		var err error
+		if d, err = time.ParseDuration(req.Duration); err != nil {
+			return err
+		}
+	}
+
+	if req.Name != "" && req.Level == "" {
+		l.ResetNameLevel(req.Name)
+		return nil
+	}
+
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(req.Level)); err != nil {
+		return err
+	}
+
+	switch {
+	case req.Hook != "":
+		return l.SetHookLevel(req.Hook, lvl, d)
+	case req.Name != "":
+		l.SetNameLevel(req.Name, lvl, d)
+	default:
+		l.SetLevel(lvl, d)
+	}
+	return nil
+}
+
+// levelHandler serves the levels of the logger returned by get, GET returns
+// the LevelState and PUT applies a LevelRequest.
+type levelHandler struct {
+	get func() *Logger
+}
+
+func (h levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	levels := h.get().levels
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req LevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeLevelError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := levels.apply(&req); err != nil {
+			writeLevelError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		writeLevelError(w, http.StatusMethodNotAllowed,
+			fmt.Errorf("method not allowed: %s", r.Method))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(levels.State())
+}
+
+func writeLevelError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLevels(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	logger, err := New(&Config{
+		Path:          path,
+		MaxSize:       1,
+		Level:         "info",
+		Format:        "json",
+		DisableStdout: true,
+		WebHook: []*hook.WebHookConfig{{
+			CoreConfig: hook.CoreConfig{Level: "error", Off: true},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	levels := logger.Levels()
+	levels.SetNameLevel("orders", zapcore.DebugLevel, 0)
+	logger.Debug("dropped")
+	logger.Named("orders").Named("matcher").Debug("child")
+	logger.Named("ordersx").Debug("dropped")
+	logger.Named("orders").Info("kept")
+	levels.ResetNameLevel("orders")
+	logger.Named("orders").Debug("dropped")
+
+	levels.SetLevel(zapcore.DebugLevel, 20*time.Millisecond)
+	logger.Debug("debug")
+	time.Sleep(50 * time.Millisecond)
+	if levels.Level() != zapcore.InfoLevel {
+		t.Fatalf("expected level reverted to info get:%s", levels.Level())
+	}
+	logger.Debug("dropped")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "dropped") {
+		t.Fatalf("unexpected entry in %s", data)
+	}
+	for _, msg := range []string{"child", "kept", `"debug"`} {
+		if !strings.Contains(string(data), msg) {
+			t.Fatalf("expected %s in %s", msg, data)
+		}
+	}
+
+	handler := logger.LevelHandler()
+	put := func(body string) (int, *LevelState) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", strings.NewReader(body)))
+		var state LevelState
+		json.Unmarshal(w.Body.Bytes(), &state)
+		return w.Code, &state
+	}
+
+	code, state := put(`{"level":"warn","hook":"webhook.0"}`)
+	if code != http.StatusOK || state.Hooks["webhook.0"] != "warn" {
+		t.Fatalf("unexpected hook level %d %+v", code, state)
+	}
+	code, state = put(`{"level":"debug","name":"orders","duration":"1h"}`)
+	if code != http.StatusOK || state.Names["orders"] != "debug" || state.Level != "info" {
+		t.Fatalf("unexpected name level %d %+v", code, state)
+	}
+	if code, _ = put(`{"level":"verbose"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected:%d get:%d", http.StatusBadRequest, code)
+	}
+	if code, _ = put(`{"level":"info","hook":"missing"}`); code != http.StatusBadRequest {
+		t.Fatalf("expected:%d get:%d", http.StatusBadRequest, code)
+	}
+}
+
+func TestLevelsRevert(t *testing.T) {
+	levels := newLevels(zapcore.InfoLevel)
+
+	// a second timed change restores the level before the first one
+	levels.SetLevel(zapcore.DebugLevel, 30*time.Millisecond)
+	levels.SetLevel(zapcore.DebugLevel, 10*time.Millisecond)
+	levels.SetNameLevel("orders", zapcore.WarnLevel, 0)
+	levels.SetNameLevel("orders", zapcore.DebugLevel, 30*time.Millisecond)
+	levels.SetNameLevel("orders", zapcore.ErrorLevel, 10*time.Millisecond)
+	levels.SetNameLevel("users", zapcore.DebugLevel, 10*time.Millisecond)
+	time.Sleep(80 * time.Millisecond)
+
+	state := levels.State()
+	if state.Level != "info" {
+		t.Errorf("expected level reverted to info get:%s", state.Level)
+	}
+	if state.Names["orders"] != "warn" || len(state.Names) != 1 {
+		t.Errorf("expected the orders override restored get:%v", state.Names)
+	}
+	if levels.Enabled(zapcore.DebugLevel) || !levels.Enabled(zapcore.WarnLevel) {
+		t.Error("expected debug disabled and warn enabled by the orders override")
+	}
+
+	// a change without duration is kept
+	levels.SetLevel(zapcore.DebugLevel, 10*time.Millisecond)
+	levels.SetLevel(zapcore.WarnLevel, 0)
+	time.Sleep(30 * time.Millisecond)
+	if levels.Level() != zapcore.WarnLevel {
+		t.Errorf("expected level warn get:%s", levels.Level())
+	}
+}
@@ -1,18 +1,23 @@
 package log
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
-	"path/filepath"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/hkjojo/go-toolkits/log/encoder"
 	"github.com/hkjojo/go-toolkits/log/hook"
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"github.com/hkjojo/go-toolkits/log/ring"
+	"github.com/hkjojo/go-toolkits/log/rotate"
 
-	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // Config ...
@@ -26,14 +31,34 @@ type Config struct {
 	MaxAge        int
 	DisableStdout bool
 	Compress      bool
-	Format        string // json/console/text
+	Format        string // json/console/text/fix/logfmt/gelf
+	FixDecode     bool   // fix format writes the FIX messages as tag(name)=value
 	ForbitTime    bool
 	ForbitLevel   bool
 	Caller        bool
+	CallerFull    bool   // full path of the caller instead of package/file
+	Stacktrace    string // level from which the stack is attached, off if empty
+	ExpandErrors  bool   // adds the wrapped errors and grpc status of the error fields
 	Prefix        string
 	Kafka         *hook.KafkaConfig
 	WebHook       []*hook.WebHookConfig
+	Telegram      []*hook.TelegramConfig
+	DingTalk      []*hook.DingTalkConfig
+	Slack         []*hook.SlackConfig
+	Lark          []*hook.LarkConfig
+	Syslog        []*hook.SyslogConfig
+	Socket        []*hook.SocketConfig
+	Fluent        []*hook.FluentConfig
+	Outputs       []*OutputConfig      // files in addition to Path
+	Sampling      *hook.SamplingConfig // of the file and stdout core
+	Redact        *redact.Config       // of every core, unless a hook has its own
 	RotateDay     int
+	Async         *AsyncConfig             // buffered writes of the file and stdout core
+	Modules       map[string]*ModuleConfig // by logger name prefix, see Named
+	Ring          *RingConfig              // last entries in memory, see RingHandler
+	StdLog        string                   // level of the stdlib log output redirected by Init, off if empty
+	Audit         *AuditConfig             // audit trail written by Audit
+	Rotate        *rotate.Config           // rotation of Path, instead of MaxSize and RotateDay
 }
 
 // SugaredLogger ..
@@ -45,12 +70,19 @@ type SugaredLogger struct {
 type Logger struct {
 	*zap.Logger
 	config *Config
+	levels *Levels
+	swap   *swapCore
+	cores  *coreSet
+	mu     sync.Mutex // serializes Reload
+	names  sync.Map   // *SugaredLogger by name, see Named
 }
 
 var (
 	// std is the name of the standard logger in stdlib `log`
 	logger = &Logger{}
 	sugger = &SugaredLogger{}
+	// helper is sugger skipping the frame of the package helpers
+	helper = &SugaredLogger{}
 )
 
 // CoreType ..
@@ -65,9 +97,18 @@ const (
 )
 
 func init() {
-	l, _ := zap.NewDevelopment()
-	logger = &Logger{l, &Config{}}
-	sugger = logger.Sugar()
+	levels := newLevels(zapcore.DebugLevel)
+	cfg := zap.NewDevelopmentConfig()
+	cfg.Level = levels.level
+	l, _ := cfg.Build()
+	setStandard(&Logger{Logger: l, config: &Config{}, levels: levels})
+}
+
+// setStandard sets the standard logger used by the package helpers.
+func setStandard(l *Logger) {
+	logger = l
+	sugger = l.Sugar()
+	helper = &SugaredLogger{l.Logger.WithOptions(zap.AddCallerSkip(1)).Sugar()}
 }
 
 // AddFields ..
@@ -90,59 +131,189 @@ type Fields map[string]interface{}
 
 // New ..
 func New(config *Config) (*Logger, error) {
-	var (
-		lvl        zapcore.Level
-		err        error
-		hooks      []zapcore.WriteSyncer
-		rotatehook *rotatelogs.RotateLogs
-		ecoder     zapcore.Encoder
-		timeKey    = "time"
-		levelKey   = "level"
-		msgKey     = "msg"
-	)
+	var lvl zapcore.Level
 	if config.Level != "" {
 		lvl = hook.ParseLevel(config.Level)
+	}
+
+	levels := newLevels(lvl)
+	levels.setModules(config.Modules)
+	cores, err := newCores(config, levels)
+	if err != nil {
+		return nil, err
+	}
+
+	swap := newSwapCore(cores.core)
+	l := zap.New(swap)
+	if config.Caller {
+		l = l.WithOptions(zap.AddCaller())
+	}
+	if config.Stacktrace != "" {
+		l = l.WithOptions(zap.AddStacktrace(hook.ParseLevel(config.Stacktrace)))
+	}
+
+	log := &Logger{
+		Logger: l,
+		config: config,
+		levels: levels,
+		swap:   swap,
+		cores:  cores,
+	}
+	swap.onFatal = &fatalCore{log}
+	return log, nil
+}
+
+// coreSet is the cores built from a config and the resources they own.
+type coreSet struct {
+	ringSeq  uint64 // of the last ring dump, first for its alignment
+	core     zapcore.Core
+	main     zapcore.Core // file and stdout core without sampling
+	hooks    []hookCore
+	closers  []io.Closer
+	asyncs   []*asyncWriter // closed before closers
+	samplers map[string]*hook.Sampler
+	ring     *ring.Core
+	audit    *auditCore
+	levels   *Levels
+	done     chan struct{}
+	once     sync.Once
+}
+
+// hookCore is implemented by the hook cores.
+type hookCore interface {
+	zapcore.Core
+	LevelSetter
+	Shutdown(ctx context.Context) error
+	Sampler() *hook.Sampler
+}
+
+// addHooks adds the n hook cores built by newCore from their config i,
+// whose base gets the default name prefix.i and redaction of config.
+func (s *coreSet) addHooks(config *Config, prefix string, n int,
+	newCore func(i int, base func(*hook.CoreConfig)) (hookCore, error)) error {
+	for i := 0; i < n; i++ {
+		core, err := newCore(i, func(c *hook.CoreConfig) {
+			if c.Name == "" {
+				c.Name = fmt.Sprintf("%s.%d", prefix, i)
+			}
+			if c.Redact == nil {
+				c.Redact = config.Redact
+			}
+		})
 		if err != nil {
-			return nil, err
+			return err
 		}
+		s.hooks = append(s.hooks, core)
 	}
+	return nil
+}
 
-	if config.Path != "" {
-		dir := getDir(config.Path)
-		if isPathNotExist(dir) {
-			if err = os.MkdirAll(dir, os.ModePerm); err != nil {
-				return nil, err
-			}
+func (s *coreSet) close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.DefaultCloseTimeout)
+	defer cancel()
+	return s.shutdown(ctx)
+}
+
+// shutdown drains the hooks concurrently then closes the files.
+func (s *coreSet) shutdown(ctx context.Context) error {
+	s.once.Do(func() {
+		close(s.done)
+		s.writeDrops()
+	})
+
+	var (
+		wg    sync.WaitGroup
+		errs  []string
+		herrs = make([]error, len(s.hooks))
+	)
+	for i, h := range s.hooks {
+		wg.Add(1)
+		go func(i int, h hookCore) {
+			defer wg.Done()
+			herrs[i] = h.Shutdown(ctx)
+		}(i, h)
+	}
+	wg.Wait()
+
+	for i, err := range herrs {
+		if err != nil {
+			errs = append(errs, s.hooks[i].Name()+": "+err.Error())
+		}
+	}
+	for _, w := range s.asyncs {
+		if err := w.Close(); err != nil {
+			errs = append(errs, err.Error())
 		}
+	}
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
 
-		if config.MaxSize != 0 {
-			hook := lumberjack.Logger{
-				Filename:   config.Path,       // log path
-				MaxSize:    config.MaxSize,    // file max size：M
-				MaxBackups: config.MaxBackups, // max backup file num
-				MaxAge:     config.MaxAge,     // file age
-				Compress:   config.Compress,   // compress gz
-			}
-			hooks = append(hooks, zapcore.AddSync(&hook))
+// async returns ws buffered if config.Async is set.
+func (s *coreSet) async(config *Config, ws zapcore.WriteSyncer) (zapcore.WriteSyncer, error) {
+	if config.Async == nil {
+		return ws, nil
+	}
+	w, err := newAsyncWriter(ws, config.Async)
+	if err != nil {
+		return nil, err
+	}
+	s.asyncs = append(s.asyncs, w)
+	return w, nil
+}
+
+func newCores(config *Config, levels *Levels) (cores *coreSet, err error) {
+	var (
+		hooks    []zapcore.WriteSyncer
+		ecoder   zapcore.Encoder
+		timeKey  = "time"
+		levelKey = "level"
+		msgKey   = "msg"
+	)
+
+	cores = &coreSet{
+		samplers: make(map[string]*hook.Sampler),
+		levels:   levels,
+		done:     make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			cores.close()
+			cores = nil
 		}
+	}()
 
-		if config.RotateDay != 0 {
-			var fn = config.Path
-			if !filepath.IsAbs(fn) {
-				v, err := filepath.Abs(fn)
-				if err != nil {
-					return nil, err
-				}
-				fn = v
-			}
+	if config.Path != "" {
+		var (
+			ws      []zapcore.WriteSyncer
+			closers []io.Closer
+		)
+		ws, closers, err = openFile(&OutputConfig{
+			Path:       config.Path,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			Compress:   config.Compress,
+			RotateDay:  config.RotateDay,
+			Rotate:     config.Rotate,
+		}, false)
+		hooks = append(hooks, ws...)
+		cores.closers = append(cores.closers, closers...)
+		if err != nil {
+			return
+		}
+	}
 
-			rotatehook, err = rotatelogs.New(
-				fn+".%Y%m%d",
-				rotatelogs.WithLinkName(fn),
-				rotatelogs.WithMaxAge(time.Hour*24*time.Duration(config.MaxAge)),
-				rotatelogs.WithRotationTime(time.Hour*24*time.Duration(config.RotateDay)),
-			)
-			hooks = append(hooks, zapcore.AddSync(rotatehook))
+	if config.Audit != nil {
+		if cores.audit, err = newAuditCore(config, cores); err != nil {
+			return
 		}
 	}
 
@@ -171,53 +342,181 @@ func New(config *Config) (*Logger, error) {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+	if config.CallerFull {
+		encoderConfig.EncodeCaller = zapcore.FullCallerEncoder
+	}
 
-	switch strings.ToLower(config.Format) {
-	case "json":
-		ecoder = zapcore.NewJSONEncoder(encoderConfig)
-	case "fix":
-		ecoder = encoder.NewFixEncoder(encoderConfig)
-	default:
-		ecoder = zapcore.NewConsoleEncoder(encoderConfig)
+	if config.FixDecode && strings.ToLower(config.Format) == encoder.FormatFix {
+		ecoder = encoder.NewFixEncoder(encoderConfig, encoder.WithFixDecode())
+	} else if ecoder, err = encoder.New(config.Format, encoderConfig); err != nil {
+		return
 	}
 
-	var cores []zapcore.Core
-	cores = append(cores, zapcore.NewCore(
-		ecoder,
-		zapcore.NewMultiWriteSyncer(hooks...),
-		lvl,
-	))
+	var tee []zapcore.Core
+	var redactor *redact.Redactor
+	if redactor, err = redact.New(config.Redact); err != nil {
+		return
+	}
+	var ws zapcore.WriteSyncer
+	if ws, err = cores.async(config, zapcore.NewMultiWriteSyncer(hooks...)); err != nil {
+		return
+	}
+	main := []zapcore.Core{zapcore.NewCore(ecoder, ws, levels)}
+	for _, o := range config.Outputs {
+		var core zapcore.Core
+		if core, err = newOutputCore(config, o, encoderConfig, cores); err != nil {
+			return
+		}
+		main = append(main, core)
+	}
+	cores.main = &levelCore{redact.NewCore(zapcore.NewTee(main...), redactor), levels}
+	modules := newModules(config.Modules)
+	sampler := hook.NewSampler(config.Sampling)
+	if sampler != nil {
+		cores.samplers["main"] = sampler
+	}
+	switch {
+	case modules.sampled():
+		for key, c := range config.Modules {
+			if mod := modules[moduleName(key)]; c != nil && mod.sampler != nil {
+				cores.samplers["module:"+key] = mod.sampler
+			}
+		}
+		tee = append(tee, &moduleCore{cores.main, modules, sampler})
+	case sampler != nil:
+		tee = append(tee, &sampleCore{cores.main, sampler})
+	default:
+		tee = append(tee, cores.main)
+	}
 
-	for _, cfg := range config.WebHook {
-		cores = append(cores, hook.NewWebHookCore(cfg, encoderConfig))
+	if err = cores.addHooks(config, "webhook", len(config.WebHook), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.WebHook[i]
+		base(&c.CoreConfig)
+		return hook.CreateWebHookCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "telegram", len(config.Telegram), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Telegram[i]
+		base(&c.CoreConfig)
+		return hook.NewTelegramCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "dingtalk", len(config.DingTalk), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.DingTalk[i]
+		base(&c.CoreConfig)
+		return hook.NewDingTalkCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "slack", len(config.Slack), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Slack[i]
+		base(&c.CoreConfig)
+		return hook.NewSlackCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "lark", len(config.Lark), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Lark[i]
+		base(&c.CoreConfig)
+		return hook.NewLarkCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "syslog", len(config.Syslog), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Syslog[i]
+		base(&c.CoreConfig)
+		return hook.NewSyslogCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "socket", len(config.Socket), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Socket[i]
+		base(&c.CoreConfig)
+		return hook.NewSocketCore(&c, encoderConfig)
+	}); err != nil {
+		return
+	}
+	if err = cores.addHooks(config, "fluent", len(config.Fluent), func(i int, base func(*hook.CoreConfig)) (hookCore, error) {
+		c := *config.Fluent[i]
+		base(&c.CoreConfig)
+		return hook.NewFluentCore(&c, encoderConfig)
+	}); err != nil {
+		return
 	}
 
 	if config.Kafka != nil {
-		core, err := hook.NewKafkaCore(config.Kafka, config.Prefix, config.Fields, encoderConfig)
+		c := *config.Kafka
+		if c.Name == "" {
+			c.Name = "kafka"
+		}
+		if c.Redact == nil {
+			c.Redact = config.Redact
+		}
+		var core *hook.KafkaCore
+		core, err = hook.NewKafkaCore(&c, config.Prefix, config.Fields, encoderConfig)
 		if err != nil {
-			return nil, err
+			return
 		}
-		cores = append(cores, core)
+		cores.hooks = append(cores.hooks, core)
 	}
 
-	core := zapcore.NewTee(cores...)
-	var l *zap.Logger
-	l = zap.New(core)
-	if config.Caller {
-		l = l.WithOptions(zap.AddCaller())
+	levels.setHooks(cores.hooks)
+	routed := modules.routed()
+	for _, h := range cores.hooks {
+		if routed {
+			tee = append(tee, &routeCore{h, h.Name(), modules})
+		} else {
+			tee = append(tee, h)
+		}
+		if sampler := h.Sampler(); sampler != nil {
+			cores.samplers[h.Name()] = sampler
+		}
+	}
+	if config.Ring != nil {
+		// first so a dump is written before the entry
+		tee = append([]zapcore.Core{redact.NewCore(cores.newRing(config.Ring), redactor)}, tee...)
+	}
+	cores.core = zapcore.NewTee(tee...)
+	if config.ExpandErrors {
+		cores.core = &errorCore{cores.core}
 	}
 
-	return &Logger{l, config}, nil
+	if len(cores.samplers) != 0 || len(cores.asyncs) != 0 {
+		go cores.reportDrops(SamplingReportInterval)
+	}
+	return
+}
+
+// Levels returns the runtime adjustable levels of the logger.
+func (log *Logger) Levels() *Levels {
+	return log.levels
+}
+
+// LevelHandler returns an http.Handler to get and adjust the levels of the
+// logger, see LevelRequest.
+func (log *Logger) LevelHandler() http.Handler {
+	return levelHandler{func() *Logger { return log }}
 }
 
 // Init ...
 func Init(config *Config) error {
-	var err error
-	logger, err = New(config)
+	l, err := New(config)
 	if err != nil {
 		return err
 	}
-	sugger = logger.Sugar()
+	setStandard(l)
+
+	if restoreStdLog != nil {
+		restoreStdLog()
+		restoreStdLog = nil
+	}
+	if config.StdLog != "" {
+		if restoreStdLog, err = logger.RedirectStdLog(config.StdLog); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -229,11 +528,3 @@ func isPathNotExist(path string) bool {
 	}
 	return false
 }
-
-func getDir(path string) string {
-	paths := strings.Split(path, "/")
-	return strings.Join(
-		paths[:len(paths)-1],
-		"/",
-	)
-}
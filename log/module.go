@@ -0,0 +1,204 @@
+package log
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap/zapcore"
+)
+
+// ModuleConfig configures the loggers named by a key of Config.Modules and
+// their children, like "orders.*" for orders and orders.matcher, "*" for
+// every logger. The longest matching key wins, its unset fields fall back
+// to the Config ones. A string is read as the Level:
+//
+//	modules:
+//	  orders.*: debug
+//	  orders.matcher:
+//	    level: info
+//	    sampling: {initial: 100, thereafter: 100}
+//	    hooks: [kafka]
+type ModuleConfig struct {
+	Level    string
+	Sampling *hook.SamplingConfig // of the file and stdout core, instead of Config.Sampling
+	Hooks    []string             // names of the hook cores written, all if nil
+}
+
+// UnmarshalJSON reads a string as the Level.
+func (m *ModuleConfig) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		*m = ModuleConfig{}
+		return json.Unmarshal(data, &m.Level)
+	}
+	type plain ModuleConfig
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// UnmarshalYAML reads a string as the Level.
+func (m *ModuleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = ModuleConfig{}
+	if err := unmarshal(&m.Level); err == nil {
+		return nil
+	}
+	type plain ModuleConfig
+	return unmarshal((*plain)(m))
+}
+
+// moduleName returns the logger name of a Config.Modules key.
+func moduleName(key string) string {
+	if key == "*" {
+		return ""
+	}
+	return strings.TrimSuffix(key, ".*")
+}
+
+// module is a ModuleConfig built.
+type module struct {
+	sampler *hook.Sampler
+	hooks   map[string]bool // nil if all
+}
+
+// modules are the built Config.Modules by logger name.
+type modules map[string]*module
+
+func newModules(config map[string]*ModuleConfig) modules {
+	m := make(modules, len(config))
+	for key, c := range config {
+		if c == nil {
+			continue
+		}
+		mod := &module{sampler: hook.NewSampler(c.Sampling)}
+		if c.Hooks != nil {
+			mod.hooks = make(map[string]bool, len(c.Hooks))
+			for _, name := range c.Hooks {
+				mod.hooks[name] = true
+			}
+		}
+		m[moduleName(key)] = mod
+	}
+	return m
+}
+
+// lookup returns the module of the logger name, nil if none.
+func (m modules) lookup(name string) *module {
+	for {
+		if mod, ok := m[name]; ok {
+			return mod
+		}
+		if name == "" {
+			return nil
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			name = ""
+		} else {
+			name = name[:i]
+		}
+	}
+}
+
+// sampled reports whether a module has sampling.
+func (m modules) sampled() bool {
+	for _, mod := range m {
+		if mod.sampler != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// routed reports whether a module selects its hook cores.
+func (m modules) routed() bool {
+	for _, mod := range m {
+		if mod.hooks != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// setModules sets the name levels of the modules, removing the ones of the
+// previous modules.
+func (l *Levels) setModules(config map[string]*ModuleConfig) {
+	names := make(map[string]bool, len(config))
+	for key, c := range config {
+		if c == nil || c.Level == "" {
+			continue
+		}
+		name := moduleName(key)
+		names[name] = true
+		l.SetNameLevel(name, hook.ParseLevel(c.Level), 0)
+	}
+
+	l.mu.Lock()
+	prev := l.modules
+	l.modules = names
+	l.mu.Unlock()
+	for name := range prev {
+		if !names[name] {
+			l.ResetNameLevel(name)
+		}
+	}
+}
+
+// moduleCore samples the entries of the main core by the sampling of their
+// module, by the main one if their module has none.
+type moduleCore struct {
+	zapcore.Core
+	modules modules
+	sampler *hook.Sampler
+}
+
+func (c *moduleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleCore{c.Core.With(fields), c.modules, c.sampler}
+}
+
+func (c *moduleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	sampler := c.sampler
+	if mod := c.modules.lookup(ent.LoggerName); mod != nil && mod.sampler != nil {
+		sampler = mod.sampler
+	}
+	if sampler != nil && !sampler.Allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// routeCore writes the entries to a hook core unless their module selects
+// other hooks.
+type routeCore struct {
+	zapcore.Core
+	name    string
+	modules modules
+}
+
+func (c *routeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &routeCore{c.Core.With(fields), c.name, c.modules}
+}
+
+func (c *routeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if mod := c.modules.lookup(ent.LoggerName); mod != nil && mod.hooks != nil && !mod.hooks[c.name] {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// named returns the cached sugared logger of name.
+func (log *Logger) named(name string) *SugaredLogger {
+	if s, ok := log.names.Load(name); ok {
+		return s.(*SugaredLogger)
+	}
+	s, _ := log.names.LoadOrStore(name, &SugaredLogger{log.Logger.Named(name).Sugar()})
+	return s.(*SugaredLogger)
+}
+
+// Named returns the standard logger named name, cached by name. Its level,
+// sampling and hooks are configured by Config.Modules and its name is
+// written under the "logger" key.
+func Named(name string) *SugaredLogger {
+	return logger.named(name)
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestModules(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path]++
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var modules map[string]*ModuleConfig
+	if err = json.Unmarshal([]byte(`{
+		"orders.*": "debug",
+		"orders.matcher": {"level": "warn"},
+		"noisy": {"sampling": {"initial": 1}},
+		"audit": {"hooks": ["a"]}
+	}`), &modules); err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := func(name string) *hook.WebHookConfig {
+		return &hook.WebHookConfig{
+			CoreConfig:  hook.CoreConfig{Name: name, QueueLength: 100, Level: "error"},
+			Host:        srv.URL + "/" + name,
+			Method:      hook.MethodPOST,
+			Message:     "{{content}}",
+			ContentType: "text/plain",
+		}
+	}
+	path := filepath.Join(dir, "app.log")
+	config := &Config{
+		Level:         "info",
+		Path:          path,
+		MaxSize:       10,
+		Format:        "logfmt",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Modules:       modules,
+		WebHook:       []*hook.WebHookConfig{webhook("a"), webhook("b")},
+	}
+	l, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.named("orders") != l.named("orders") {
+		t.Error("not cached")
+	}
+	l.named("orders.book").Debug("book")
+	l.named("orders.matcher").Info("match")
+	l.named("orders.matcher").Warn("matched")
+	l.named("other").Debug("other")
+	for i := 0; i < 3; i++ {
+		l.named("noisy").Info("n")
+	}
+	l.named("audit").Error("audit")
+	l.Error("main")
+
+	// the modules removed by a reload restore the main level
+	reloaded := *config
+	reloaded.Modules = nil
+	if err = l.Reload(&reloaded); err != nil {
+		t.Fatal(err)
+	}
+	if l.levels.NameEnabled("orders.book", zapcore.DebugLevel) {
+		t.Error("module level kept")
+	}
+	if err = l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `level=DEBUG logger=orders.book msg=book
+level=WARN logger=orders.matcher msg=matched
+level=INFO logger=noisy msg=n
+level=ERROR logger=audit msg=audit
+level=ERROR msg=main
+level=WARN msg="log entries dropped by sampling" core=module:noisy info=2
+`
+	if string(data) != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, data)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received["/a"] != 2 || received["/b"] != 1 {
+		t.Errorf("received %v", received)
+	}
+}
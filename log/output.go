@@ -0,0 +1,256 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/encoder"
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"github.com/hkjojo/go-toolkits/log/rotate"
+	rotatelogs "github.com/lestrrat-go/file-rotatelogs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// OutputConfig is a file written by the main core in addition to Path, e.g.
+// app.error.log with warn and above or access.log with the access logger.
+// The file is rotated by size if MaxSize is set, by days if RotateDay is,
+// by both with a single writer if both are, or as configured by Rotate.
+type OutputConfig struct {
+	Path       string
+	Level      string         // min level, default debug
+	MaxLevel   string         // default fatal
+	Format     string         // default Config.Format
+	MaxSize    int            // M
+	MaxBackups int            // max backup file num
+	MaxAge     int            // days
+	Compress   bool           // compress gz
+	RotateDay  int            // days
+	Filter     []string       // fields dropped
+	Loggers    []string       // names written with their children, all if empty
+	Exclude    []string       // names not written with their children
+	Rotate     *rotate.Config // instead of MaxSize and RotateDay, Filename defaults to Path
+}
+
+// openFile opens the writers of a file rotated by size and/or days, a file
+// without rotation is opened if plain.
+func openFile(o *OutputConfig, plain bool) (ws []zapcore.WriteSyncer, closers []io.Closer, err error) {
+	dir := filepath.Dir(o.Path)
+	if isPathNotExist(dir) {
+		if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+			return
+		}
+	}
+
+	if o.Rotate != nil || (o.MaxSize != 0 && o.RotateDay != 0) {
+		var w *rotate.Writer
+		if w, err = rotate.New(rotateConfig(o)); err != nil {
+			return
+		}
+		ws = append(ws, zapcore.AddSync(w))
+		closers = append(closers, w)
+		return
+	}
+
+	if o.MaxSize != 0 {
+		hook := &lumberjack.Logger{
+			Filename:   o.Path,       // log path
+			MaxSize:    o.MaxSize,    // file max size：M
+			MaxBackups: o.MaxBackups, // max backup file num
+			MaxAge:     o.MaxAge,     // file age
+			Compress:   o.Compress,   // compress gz
+		}
+		ws = append(ws, zapcore.AddSync(hook))
+		closers = append(closers, hook)
+	}
+
+	if o.RotateDay != 0 {
+		var fn = o.Path
+		if !filepath.IsAbs(fn) {
+			if fn, err = filepath.Abs(fn); err != nil {
+				return
+			}
+		}
+
+		var rotatehook *rotatelogs.RotateLogs
+		rotatehook, err = rotatelogs.New(
+			fn+".%Y%m%d",
+			rotatelogs.WithLinkName(fn),
+			rotatelogs.WithMaxAge(time.Hour*24*time.Duration(o.MaxAge)),
+			rotatelogs.WithRotationTime(time.Hour*24*time.Duration(o.RotateDay)),
+		)
+		if err != nil {
+			return
+		}
+		ws = append(ws, zapcore.AddSync(rotatehook))
+		closers = append(closers, rotatehook)
+	}
+
+	if len(ws) == 0 && plain {
+		var f *os.File
+		if f, err = os.OpenFile(o.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			return
+		}
+		ws = append(ws, f)
+		closers = append(closers, f)
+	}
+	return
+}
+
+// rotateConfig returns the rotation of o, the one of MaxSize and RotateDay
+// if Rotate is not set.
+func rotateConfig(o *OutputConfig) *rotate.Config {
+	if o.Rotate != nil {
+		c := *o.Rotate
+		if c.Filename == "" {
+			c.Filename = o.Path
+		}
+		return &c
+	}
+
+	c := &rotate.Config{
+		Filename:   o.Path,
+		Pattern:    o.Path + ".%Y%m%d",
+		MaxSize:    o.MaxSize,
+		Rotation:   rotate.Daily,
+		MaxBackups: o.MaxBackups,
+		MaxAge:     o.MaxAge,
+	}
+	if o.RotateDay > 1 {
+		c.Rotation = (time.Hour * 24 * time.Duration(o.RotateDay)).String()
+	}
+	if o.Compress {
+		c.Compress = rotate.Gzip
+	}
+	return c
+}
+
+// newOutputCore returns the core of an output, its closers are added to
+// cores.
+func newOutputCore(config *Config, o *OutputConfig, encoderConfig zapcore.EncoderConfig,
+	cores *coreSet) (zapcore.Core, error) {
+	format := o.Format
+	if format == "" {
+		format = config.Format
+	}
+	var (
+		enc zapcore.Encoder
+		err error
+	)
+	if config.FixDecode && strings.ToLower(format) == encoder.FormatFix {
+		enc = encoder.NewFixEncoder(encoderConfig, encoder.WithFixDecode())
+	} else if enc, err = encoder.New(format, encoderConfig); err != nil {
+		return nil, err
+	}
+
+	ws, closers, err := openFile(o, true)
+	cores.closers = append(cores.closers, closers...)
+	if err != nil {
+		return nil, err
+	}
+	w, err := cores.async(config, zapcore.NewMultiWriteSyncer(ws...))
+	if err != nil {
+		return nil, err
+	}
+
+	min, max := zapcore.DebugLevel, zapcore.FatalLevel
+	if o.Level != "" {
+		min = hook.ParseLevel(o.Level)
+	}
+	if o.MaxLevel != "" {
+		max = hook.ParseLevel(o.MaxLevel)
+	}
+	enabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return lvl >= min && lvl <= max
+	})
+
+	c := &outputCore{
+		Core:    zapcore.NewCore(enc, w, enabler),
+		loggers: o.Loggers,
+		exclude: o.Exclude,
+	}
+	if len(o.Filter) != 0 {
+		c.filters = make(map[string]bool, len(o.Filter))
+		for _, key := range o.Filter {
+			c.filters[key] = true
+		}
+	}
+	return c, nil
+}
+
+// outputCore writes the entries of its loggers without the filtered fields.
+type outputCore struct {
+	zapcore.Core
+	loggers []string
+	exclude []string
+	filters map[string]bool
+}
+
+func (c *outputCore) With(fields []zapcore.Field) zapcore.Core {
+	return &outputCore{c.Core.With(c.filter(fields)), c.loggers, c.exclude, c.filters}
+}
+
+func (c *outputCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) && c.logger(ent.LoggerName) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write checks the entry again, the wrappers of the main core write to the
+// whole tee.
+func (c *outputCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) || !c.logger(ent.LoggerName) {
+		return nil
+	}
+	return c.Core.Write(ent, c.filter(fields))
+}
+
+// logger reports whether the entries of the named logger are written.
+func (c *outputCore) logger(name string) bool {
+	for _, n := range c.exclude {
+		if nameMatch(n, name) {
+			return false
+		}
+	}
+	if len(c.loggers) == 0 {
+		return true
+	}
+	for _, n := range c.loggers {
+		if nameMatch(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns fields without the filtered ones, fields is returned as is
+// if none is filtered.
+func (c *outputCore) filter(fields []zapcore.Field) []zapcore.Field {
+	if c.filters == nil {
+		return fields
+	}
+	for i, f := range fields {
+		if !c.filters[f.Key] {
+			continue
+		}
+		kept := make([]zapcore.Field, i, len(fields))
+		copy(kept, fields[:i])
+		for _, f := range fields[i+1:] {
+			if !c.filters[f.Key] {
+				kept = append(kept, f)
+			}
+		}
+		return kept
+	}
+	return fields
+}
+
+// nameMatch reports whether name is the logger n or one of its children.
+func nameMatch(n, name string) bool {
+	return name == n || strings.HasPrefix(name, n+".")
+}
@@ -0,0 +1,111 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestOutputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := New(&Config{
+		Level:         "debug",
+		Format:        "json",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Outputs: []*OutputConfig{
+			{Path: filepath.Join(dir, "app.log"), Level: "info", Exclude: []string{"access"}},
+			{Path: filepath.Join(dir, "app.error.log"), Level: "warn", Format: "logfmt"},
+			{Path: filepath.Join(dir, "access", "access.log"), Loggers: []string{"access"},
+				Filter: []string{"password"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn", zap.Int("n", 1))
+	access := l.Named("access").With(zap.String("password", "secret"))
+	access.Info("GET /", zap.String("user", "bob"), zap.String("password", "secret"))
+	l.Named("access.http").Error("failed")
+
+	if err = l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	expected := map[string]string{
+		"app.log": `{"level":"INFO","msg":"info"}` + "\n" +
+			`{"level":"WARN","msg":"warn","n":1}` + "\n",
+		"app.error.log": `level=WARN msg=warn n=1` + "\n" +
+			`level=ERROR logger=access.http msg=failed` + "\n",
+		"access/access.log": `{"level":"INFO","logger":"access","msg":"GET /","user":"bob"}` + "\n" +
+			`{"level":"ERROR","logger":"access.http","msg":"failed"}` + "\n",
+	}
+	for name, content := range expected {
+		if get := read(name); get != content {
+			t.Errorf("%s\nexpected:%s\n     get:%s", name, content, get)
+		}
+	}
+	if strings.Contains(read("access/access.log"), "secret") {
+		t.Error("password not filtered")
+	}
+}
+
+func TestOutputRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// size and days rotated by a single writer, each line written once
+	l, err := New(&Config{
+		Format:        "logfmt",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Path:          filepath.Join(dir, "app.log"),
+		MaxSize:       1,
+		RotateDay:     1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Info("once")
+	if err = l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("files %d", len(infos))
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "level=INFO msg=once\n" {
+		t.Errorf("content %q", data)
+	}
+}
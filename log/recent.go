@@ -0,0 +1,131 @@
+package log
+
+import (
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"github.com/hkjojo/go-toolkits/log/ring"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RingConfig keeps the last entries in memory from Level, whatever the
+// level of the other cores, served by RingHandler. With Dump the entries
+// the file and stdout core skipped are written to it before an entry of
+// Dump or above, e.g. the debug entries leading to an error.
+type RingConfig struct {
+	Size  int    // entries, default 1000
+	Level string // default debug
+	Dump  string // off if empty
+}
+
+// newRing returns the core of the ring, dumping to cores.main if set.
+func (s *coreSet) newRing(config *RingConfig) zapcore.Core {
+	lvl := zapcore.DebugLevel
+	if config.Level != "" {
+		lvl = hook.ParseLevel(config.Level)
+	}
+	s.ring = ring.New(config.Size, lvl)
+	if config.Dump == "" {
+		return s.ring
+	}
+	return &dumpCore{s.ring, s, hook.ParseLevel(config.Dump)}
+}
+
+// dumpCore writes the entries of the ring the main core skipped to the
+// main core before the entries from its level.
+type dumpCore struct {
+	zapcore.Core
+	cores *coreSet
+	level zapcore.Level
+}
+
+func (c *dumpCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dumpCore{c.Core.With(fields), c.cores, c.level}
+}
+
+func (c *dumpCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dumpCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level >= c.level {
+		c.cores.dumpRing()
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// dumpRing writes the entries kept since the last dump which the main core
+// skipped to it.
+func (s *coreSet) dumpRing() {
+	after := atomic.LoadUint64(&s.ringSeq)
+	entries := s.ring.Query(&ring.Query{After: after})
+	if len(entries) == 0 ||
+		!atomic.CompareAndSwapUint64(&s.ringSeq, after, entries[len(entries)-1].Seq) {
+		return
+	}
+
+	for _, e := range entries {
+		if s.levels.NameEnabled(e.Logger, e.Level) {
+			continue
+		}
+		keys := make([]string, 0, len(e.Fields))
+		for key := range e.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fields := make([]zapcore.Field, len(keys))
+		for i, key := range keys {
+			fields[i] = zap.Any(key, e.Fields[key])
+		}
+		s.main.Write(zapcore.Entry{
+			Level:      e.Level,
+			Time:       e.Time,
+			LoggerName: e.Logger,
+			Message:    e.Message,
+			Stack:      e.Stack,
+		}, fields)
+	}
+}
+
+// Ring returns the ring of the logger, nil if Config.Ring is not set.
+func (log *Logger) Ring() *ring.Core {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.cores == nil {
+		return nil
+	}
+	return log.cores.ring
+}
+
+// RingHandler returns an http.Handler serving the entries of the ring of
+// the logger, see ring.Core.ServeHTTP.
+func (log *Logger) RingHandler() http.Handler {
+	return ringHandler{func() *Logger { return log }}
+}
+
+// RingHandler returns an http.Handler serving the entries of the ring of
+// the standard logger, including the one set by a later Init.
+//
+//	curl 'localhost:8080/log/recent?level=debug&logger=orders&limit=100'
+func RingHandler() http.Handler {
+	return ringHandler{StandardLogger}
+}
+
+type ringHandler struct {
+	get func() *Logger
+}
+
+func (h ringHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c := h.get().Ring()
+	if c == nil {
+		http.Error(w, "log ring is not configured", http.StatusNotFound)
+		return
+	}
+	c.ServeHTTP(w, r)
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestRing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ring")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	l, err := New(&Config{
+		Level:         "info",
+		Path:          path,
+		MaxSize:       10,
+		Format:        "logfmt",
+		DisableStdout: true,
+		ForbitTime:    true,
+		Ring:          &RingConfig{Size: 10, Dump: "error"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Debug("a", zap.Int("n", 1))
+	l.Info("b")
+	l.Error("c")
+	l.Error("d")
+
+	if n := len(l.Ring().Entries()); n != 4 {
+		t.Errorf("ring entries %d", n)
+	}
+	w := httptest.NewRecorder()
+	l.RingHandler().ServeHTTP(w, httptest.NewRequest("GET", "/?level=debug&msg=a", nil))
+	if !strings.Contains(w.Body.String(), `"message":"a","fields":{"n":1}`) {
+		t.Errorf("handler %s", w.Body)
+	}
+
+	if err = l.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the debug entry skipped is written before the first error
+	expected := "level=INFO msg=b\nlevel=DEBUG msg=a n=1\nlevel=ERROR msg=c\nlevel=ERROR msg=d\n"
+	if string(data) != expected {
+		t.Errorf("\nexpected:%s\n     get:%s", expected, data)
+	}
+
+	if l, err = New(&Config{DisableStdout: true}); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	l.RingHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 404 {
+		t.Errorf("no ring %d", w.Code)
+	}
+}
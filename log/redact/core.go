@@ -0,0 +1,34 @@
+package redact
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+type core struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+// NewCore wraps c to mask the message and fields of its entries.
+func NewCore(c zapcore.Core, r *Redactor) zapcore.Core {
+	if r == nil {
+		return c
+	}
+	return &core{c, r}
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	return &core{c.Core.With(c.redactor.Fields(fields)), c.redactor}
+}
+
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.redactor.String(ent.Message)
+	return c.Core.Write(ent, c.redactor.Fields(fields))
+}
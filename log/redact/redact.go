@@ -0,0 +1,355 @@
+// Package redact masks the sensitive fields and values of log entries.
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Strategies
+const (
+	StrategyFull    = "full"    // replaced by ***
+	StrategyPartial = "partial" // all but the last Keep characters masked
+	StrategyHash    = "hash"    // replaced by a short sha256
+)
+
+// Detectors
+const (
+	DetectCard   = "card"
+	DetectEmail  = "email"
+	DetectPhone  = "phone"
+	DetectBearer = "bearer"
+)
+
+// DefaultKeys are the key patterns masked if Config.Keys is nil.
+var DefaultKeys = []string{
+	"*password*", "*passwd*", "*secret*", "*token*", "authorization",
+	"*api_key*", "*apikey*", "cookie", "set-cookie",
+}
+
+// DefaultKeep is the number of characters kept by partial masking.
+const DefaultKeep = 4
+
+const fullMask = "***"
+
+// Config ..
+type Config struct {
+	Keys      []string // glob like "*password*", or regexp between slashes like "/^x-.*-key$/", case insensitive
+	Detectors []string // values detected in strings: card/email/phone/bearer
+	Strategy  string   // full/partial/hash, default full
+	Keep      int      // characters kept by partial masking, default 4
+}
+
+// Redactor masks the fields matching its config, nested ones included.
+type Redactor struct {
+	globs     []string
+	regexps   []*regexp.Regexp
+	detectors []detector
+	strategy  string
+	keep      int
+}
+
+type detector struct {
+	re    *regexp.Regexp
+	valid func(match string) bool
+	mask  func(r *Redactor, match string) string
+}
+
+var (
+	cardRe   = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	emailRe  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phoneRe  = regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\b\d{3,4}[ .-]?\d{3,4}(?:[ .-]?\d{2,4})?\b`)
+	bearerRe = regexp.MustCompile(`(?i)\b(bearer\s+)([A-Za-z0-9\-._~+/]+=*)`)
+)
+
+// New returns nil if config is nil.
+func New(config *Config) (*Redactor, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	r := &Redactor{strategy: config.Strategy, keep: config.Keep}
+	if r.strategy == "" {
+		r.strategy = StrategyFull
+	}
+	switch r.strategy {
+	case StrategyFull, StrategyPartial, StrategyHash:
+	default:
+		return nil, fmt.Errorf("redact: unknown strategy %q", r.strategy)
+	}
+	if r.keep <= 0 {
+		r.keep = DefaultKeep
+	}
+
+	keys := config.Keys
+	if keys == nil {
+		keys = DefaultKeys
+	}
+	for _, key := range keys {
+		if len(key) > 1 && strings.HasPrefix(key, "/") && strings.HasSuffix(key, "/") {
+			re, err := regexp.Compile("(?i)" + key[1:len(key)-1])
+			if err != nil {
+				return nil, fmt.Errorf("redact: key %s: %v", key, err)
+			}
+			r.regexps = append(r.regexps, re)
+			continue
+		}
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("redact: key %s: %v", key, err)
+		}
+		r.globs = append(r.globs, strings.ToLower(key))
+	}
+
+	for _, name := range config.Detectors {
+		switch strings.ToLower(name) {
+		case DetectCard:
+			r.detectors = append(r.detectors, detector{re: cardRe, valid: luhn})
+		case DetectEmail:
+			r.detectors = append(r.detectors, detector{re: emailRe})
+		case DetectPhone:
+			r.detectors = append(r.detectors, detector{re: phoneRe, valid: phone})
+		case DetectBearer:
+			r.detectors = append(r.detectors, detector{re: bearerRe, mask: maskBearer})
+		default:
+			return nil, fmt.Errorf("redact: unknown detector %q", name)
+		}
+	}
+	return r, nil
+}
+
+// Key reports whether the values of key are masked.
+func (r *Redactor) Key(key string) bool {
+	key = strings.ToLower(key)
+	for _, g := range r.globs {
+		if ok, _ := path.Match(g, key); ok {
+			return true
+		}
+	}
+	for _, re := range r.regexps {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask masks s with the strategy of the redactor.
+func (r *Redactor) Mask(s string) string {
+	switch r.strategy {
+	case StrategyPartial:
+		n := utf8.RuneCountInString(s)
+		if n <= r.keep {
+			return fullMask
+		}
+		runes := []rune(s)
+		return strings.Repeat("*", n-r.keep) + string(runes[n-r.keep:])
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return fullMask
+}
+
+// String masks the values detected in s.
+func (r *Redactor) String(s string) string {
+	for _, d := range r.detectors {
+		d := d
+		s = d.re.ReplaceAllStringFunc(s, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if d.mask != nil {
+				return d.mask(r, match)
+			}
+			return r.Mask(match)
+		})
+	}
+	return s
+}
+
+// Fields returns fields with the sensitive ones masked, fields is returned
+// as is if nothing is masked.
+func (r *Redactor) Fields(fields []zapcore.Field) []zapcore.Field {
+	var masked []zapcore.Field
+	for i, f := range fields {
+		mf, ok := r.Field(f)
+		if !ok {
+			if masked != nil {
+				masked = append(masked, f)
+			}
+			continue
+		}
+		if masked == nil {
+			masked = make([]zapcore.Field, i, len(fields))
+			copy(masked, fields[:i])
+		}
+		masked = append(masked, mf)
+	}
+	if masked == nil {
+		return fields
+	}
+	return masked
+}
+
+// Field masks f, reporting whether it changed. Objects, arrays and
+// reflected values are masked recursively.
+func (r *Redactor) Field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if r.Key(f.Key) {
+		return zap.String(f.Key, r.Mask(fieldString(f))), true
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		if s := r.String(f.String); s != f.String {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		b := f.Interface.([]byte)
+		if s := r.String(string(b)); s != string(b) {
+			return zap.String(f.Key, s), true
+		}
+	case zapcore.ErrorType, zapcore.StringerType:
+		s := fieldString(f)
+		if m := r.String(s); m != s {
+			return zap.String(f.Key, m), true
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if v, ok := r.value(enc.Fields[f.Key]); ok {
+			return zap.Reflect(f.Key, v), true
+		}
+	case zapcore.ReflectType:
+		if v, ok := r.value(f.Interface); ok {
+			return zap.Reflect(f.Key, v), true
+		}
+	}
+	return f, false
+}
+
+// value masks the nested keys and strings of v, reporting whether it changed.
+func (r *Redactor) value(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		// masked into a copy, v may belong to the caller
+		var changed bool
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = e
+			if r.Key(k) {
+				out[k] = r.Mask(fmt.Sprint(e))
+				changed = true
+			} else if m, ok := r.value(e); ok {
+				out[k] = m
+				changed = true
+			}
+		}
+		if !changed {
+			return v, false
+		}
+		return out, true
+	case []interface{}:
+		var changed bool
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = e
+			if m, ok := r.value(e); ok {
+				out[i] = m
+				changed = true
+			}
+		}
+		if !changed {
+			return v, false
+		}
+		return out, true
+	case string:
+		s := r.String(v)
+		return s, s != v
+	case nil, bool, json.Number, float64, float32, int, int64, int32, uint, uint64, uint32:
+		return v, false
+	}
+
+	// a reflected value is walked through its json form
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v, false
+	}
+	var generic interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if dec.Decode(&generic) != nil {
+		return v, false
+	}
+	if m, ok := r.value(generic); ok {
+		return m, true
+	}
+	return v, false
+}
+
+// fieldString returns the value of f as it is printed by fmt.
+func fieldString(f zapcore.Field) string {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String
+	case zapcore.ErrorType:
+		return f.Interface.(error).Error()
+	case zapcore.StringerType:
+		return f.Interface.(fmt.Stringer).String()
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		return string(f.Interface.([]byte))
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return fmt.Sprint(enc.Fields[f.Key])
+}
+
+// luhn validates the checksum of a card number.
+func luhn(s string) bool {
+	var sum, n int
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// phone requires 9 to 15 digits, the length of an international number.
+func phone(s string) bool {
+	var n int
+	for i := 0; i < len(s); i++ {
+		if s[i] >= '0' && s[i] <= '9' {
+			n++
+		}
+	}
+	return n >= 9 && n <= 15
+}
+
+func maskBearer(r *Redactor, match string) string {
+	m := bearerRe.FindStringSubmatch(match)
+	return m[1] + r.Mask(m[2])
+}
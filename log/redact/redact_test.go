@@ -0,0 +1,82 @@
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type account struct {
+	Name     string
+	Password string
+	Cards    []string
+}
+
+func (a account) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("name", a.Name)
+	enc.AddString("password", a.Password)
+	return enc.AddReflected("cards", a.Cards)
+}
+
+func TestRedactor(t *testing.T) {
+	r, err := New(&Config{
+		Keys:      []string{"*password*", "/^x-.*-key$/"},
+		Detectors: []string{DetectCard, DetectEmail, DetectPhone, DetectBearer},
+		Strategy:  StrategyPartial,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in       string
+		expected string
+	}{
+		{"card 4111 1111 1111 1111 paid", "card ***************1111 paid"},
+		{"order 4111111111111112", "order 4111111111111112"},
+		{"mail bob@example.com", "mail ***********.com"},
+		{"call +86 138-0013-8000", "call *************8000"},
+		{"at 2018-06-19 16:33:42 took 1500ms", "at 2018-06-19 16:33:42 took 1500ms"},
+		{"Authorization: Bearer eyJhbGciOi.J9", "Authorization: Bearer *********i.J9"},
+	}
+	for _, tt := range tests {
+		if got := r.String(tt.in); got != tt.expected {
+			t.Errorf("String(%q) = %q, expected %q", tt.in, got, tt.expected)
+		}
+	}
+
+	req := map[string]interface{}{
+		"headers": map[string]interface{}{"x-auth-key": "secret1"},
+		"amount":  12.5,
+	}
+	fields := r.Fields([]zapcore.Field{
+		zap.String("user_password", "hunter22"),
+		zap.String("X-Api-Key", "abcdef"),
+		zap.Int("age", 12),
+		zap.Error(errors.New("no card bob@example.com")),
+		zap.Object("account", account{"bob", "hunter22", []string{"4111111111111111"}}),
+		zap.Reflect("req", req),
+	})
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	data, _ := json.Marshal(enc.Fields)
+	expected := `{"X-Api-Key":"**cdef","account":{"cards":["************1111"],"name":"bob","password":"****er22"},"age":12,"error":"no card ***********.com","req":{"amount":12.5,"headers":{"x-auth-key":"***ret1"}},"user_password":"****er22"}`
+	if string(data) != expected {
+		t.Fatalf("unexpected fields\n%s\n%s", data, expected)
+	}
+
+	if req["headers"].(map[string]interface{})["x-auth-key"] != "secret1" {
+		t.Fatal("expected reflected value left unchanged")
+	}
+
+	unchanged := []zapcore.Field{zap.String("name", "bob")}
+	if got := r.Fields(unchanged); &got[0] != &unchanged[0] {
+		t.Fatal("expected fields returned as is")
+	}
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"github.com/hkjojo/go-toolkits/log/redact"
+	"go.uber.org/zap"
+)
+
+func TestRedact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(dir, "app.log")
+	logger, err := New(&Config{
+		Path:          path,
+		MaxSize:       1,
+		Format:        "json",
+		DisableStdout: true,
+		Redact: &redact.Config{
+			Detectors: []string{redact.DetectEmail},
+			Strategy:  redact.StrategyPartial,
+		},
+		WebHook: []*hook.WebHookConfig{{
+			CoreConfig: hook.CoreConfig{QueueLength: 10, Level: "error"},
+			Host:       srv.URL,
+			Message:    "{{.Message}} {{content}}",
+			KVMessage:  "{{key}}={{value}}",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.With(zap.String("api_token", "abcdefgh")).
+		Error("signup bob@example.com", zap.String("password", "hunter22"))
+	logger.Close(context.Background())
+
+	data, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"signup ***********.com","api_token":"****efgh","password":"****er22"`) {
+		t.Fatalf("unexpected file %s", data)
+	}
+	if body != "signup ***********.com api_token=****efghpassword=****er22" {
+		t.Fatalf("unexpected webhook %s", body)
+	}
+}
@@ -0,0 +1,179 @@
+package log
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap/zapcore"
+)
+
+// swapCore delegates to the current core of a Logger so Reload can replace
+// it under concurrent logging. Cores derived by With follow reloads by
+// applying their fields again to the new core.
+type swapCore struct {
+	current *atomic.Value // *currentCore
+	fields  []zapcore.Field
+	onFatal zapcore.Core // checked after the current core
+
+	mu      sync.Mutex
+	derived atomic.Value // *derivedCore
+}
+
+// currentCore boxes the current core so derived cores compare it by
+// pointer, a tee of cores is not comparable.
+type currentCore struct {
+	core zapcore.Core
+}
+
+type derivedCore struct {
+	base *currentCore
+	core zapcore.Core
+}
+
+func newSwapCore(core zapcore.Core) *swapCore {
+	current := &atomic.Value{}
+	current.Store(&currentCore{core})
+	return &swapCore{current: current}
+}
+
+func (c *swapCore) load() zapcore.Core {
+	base := c.current.Load().(*currentCore)
+	if len(c.fields) == 0 {
+		return base.core
+	}
+
+	if d, ok := c.derived.Load().(*derivedCore); ok && d.base == base {
+		return d.core
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.derived.Load().(*derivedCore); ok && d.base == base {
+		return d.core
+	}
+	d := &derivedCore{base: base, core: base.core.With(c.fields)}
+	c.derived.Store(d)
+	return d.core
+}
+
+func (c *swapCore) store(core zapcore.Core) {
+	c.current.Store(&currentCore{core})
+}
+
+func (c *swapCore) Enabled(lvl zapcore.Level) bool {
+	return c.load().Enabled(lvl)
+}
+
+func (c *swapCore) With(fields []zapcore.Field) zapcore.Core {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return &swapCore{current: c.current, fields: all, onFatal: c.onFatal}
+}
+
+func (c *swapCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	ce = c.load().Check(ent, ce)
+	if c.onFatal != nil {
+		ce = c.onFatal.Check(ent, ce)
+	}
+	return ce
+}
+
+func (c *swapCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.load().Write(ent, fields)
+}
+
+func (c *swapCore) Sync() error {
+	return c.load().Sync()
+}
+
+// Reload applies config to the logger. A change of Level only adjusts the
+// level, any other change rebuilds the cores, swaps them in and closes the
+// replaced ones. Caller and Stacktrace take effect on a new Logger only.
+func (log *Logger) Reload(config *Config) error {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	if log.swap == nil {
+		return errors.New("log: logger is not reloadable")
+	}
+
+	if config.Level != log.config.Level {
+		log.levels.SetLevel(hook.ParseLevel(config.Level), 0)
+	}
+	if !reflect.DeepEqual(config.Modules, log.config.Modules) {
+		log.levels.setModules(config.Modules)
+	}
+
+	old := *log.config
+	cur := *config
+	old.Level, cur.Level = "", ""
+	old.Caller, cur.Caller = false, false
+	old.Stacktrace, cur.Stacktrace = "", ""
+	if reflect.DeepEqual(&old, &cur) {
+		log.config = config
+		return nil
+	}
+
+	cores, err := newCores(config, log.levels)
+	if err != nil {
+		return err
+	}
+
+	replaced := log.cores
+	log.swap.store(cores.core)
+	log.cores = cores
+	log.config = config
+	return replaced.close()
+}
+
+// Scanner is a watched config value, satisfied by the reader.Value of
+// go-micro config used by microtools.
+type Scanner interface {
+	Scan(v interface{}) error
+}
+
+// Reload applies config to the standard logger, see Logger.Reload.
+// It initializes the standard logger if Init has not been called.
+func Reload(config *Config) error {
+	if logger.swap == nil {
+		return Init(config)
+	}
+	return logger.Reload(config)
+}
+
+// Watch reloads the standard logger every time the watched config changes.
+// watch subscribes the given callback to the config source, usually
+//
+//	log.Watch(func(reload func(log.Scanner, error)) error {
+//		return microtools.ConfigWatch(func(v reader.Value, err error) {
+//			reload(v, err)
+//		}, "log")
+//	})
+func Watch(watch func(reload func(Scanner, error)) error) error {
+	return watch(func(v Scanner, err error) {
+		if err != nil {
+			Errorw("log config watch fail", "error", err)
+			return
+		}
+		if v == nil {
+			return
+		}
+
+		var config Config
+		if err := v.Scan(&config); err != nil {
+			Errorw("log config scan fail", "error", err)
+			return
+		}
+		if reflect.DeepEqual(&config, &Config{}) {
+			return
+		}
+
+		if err := Reload(&config); err != nil {
+			Errorw("log config reload fail", "error", err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap"
+)
+
+type jsonScanner string
+
+func (s jsonScanner) Scan(v interface{}) error {
+	return json.Unmarshal([]byte(s), v)
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+	logger, err := New(&Config{
+		Path:          first,
+		MaxSize:       1,
+		Level:         "info",
+		Format:        "json",
+		DisableStdout: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	derived := logger.With(zap.String("module", "orders"))
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				derived.Info("concurrent")
+			}
+		}
+	}()
+
+	derived.Info("before")
+	if err := logger.Reload(&Config{
+		Path:          second,
+		MaxSize:       1,
+		Level:         "debug",
+		Format:        "json",
+		DisableStdout: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	derived.Debug("after")
+	close(stop)
+	wg.Wait()
+
+	data, _ := ioutil.ReadFile(first)
+	if !strings.Contains(string(data), "before") || strings.Contains(string(data), "after") {
+		t.Fatalf("unexpected first file %s", data)
+	}
+	data, _ = ioutil.ReadFile(second)
+	if !strings.Contains(string(data), `"msg":"after","module":"orders"`) {
+		t.Fatalf("unexpected second file %s", data)
+	}
+
+	// a level change only does not rebuild the cores
+	cores := logger.cores
+	if err := logger.Reload(&Config{
+		Path:          second,
+		MaxSize:       1,
+		Level:         "warn",
+		Format:        "json",
+		DisableStdout: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if logger.cores != cores || logger.Levels().Level().String() != "warn" {
+		t.Fatal("expected level change without rebuild")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	var reload func(Scanner, error)
+	err = Watch(func(f func(Scanner, error)) error {
+		reload = f
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reload(jsonScanner(`{"Path":"`+path+`","MaxSize":1,"Level":"info","DisableStdout":true}`), nil)
+	Info("watched")
+	reload(jsonScanner(`{"Path":"`+path+`","MaxSize":1,"Level":"error","DisableStdout":true}`), nil)
+	Info("dropped")
+	Sync()
+
+	data, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(data), "watched") || strings.Contains(string(data), "dropped") {
+		t.Fatalf("unexpected file %s", data)
+	}
+}
+
+func TestReloadWithHook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	config := func(hookLevel string) *Config {
+		return &Config{
+			Level:         "info",
+			DisableStdout: true,
+			WebHook: []*hook.WebHookConfig{{
+				CoreConfig: hook.CoreConfig{QueueLength: 10, Level: hookLevel},
+				Host:       srv.URL,
+			}},
+		}
+	}
+	logger, err := New(config("error"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the base core is a tee, the derived core has to follow it
+	derived := logger.With(zap.String("module", "orders")).Sugar().With("a", 1)
+	derived.Info("before")
+	derived.Info("cached")
+	cores := logger.cores
+	if err := logger.Reload(config("fatal")); err != nil {
+		t.Fatal(err)
+	}
+	if logger.cores == cores {
+		t.Fatal("expected the cores rebuilt")
+	}
+	derived.Info("after")
+}
@@ -0,0 +1,61 @@
+package ring
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ServeHTTP writes the entries matching the query parameters as JSON, the
+// oldest first:
+//
+//	curl 'localhost:8080/log/recent?level=warn&logger=orders&msg=fill&field=user=bob&limit=100'
+//
+// after returns the entries with a Seq above it, to poll the new ones.
+func (c *Core) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q, err := parseQuery(r)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries := c.Query(q)
+	if entries == nil {
+		entries = []Entry{}
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+func parseQuery(r *http.Request) (*Query, error) {
+	v := r.URL.Query()
+	q := &Query{
+		Level:   v.Get("level"),
+		Logger:  v.Get("logger"),
+		Message: v.Get("msg"),
+	}
+	var err error
+	if s := v.Get("after"); s != "" {
+		if q.After, err = strconv.ParseUint(s, 10, 64); err != nil {
+			return nil, err
+		}
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range v["field"] {
+		i := strings.IndexByte(f, '=')
+		if i < 0 {
+			continue
+		}
+		if q.Fields == nil {
+			q.Fields = make(map[string]string)
+		}
+		q.Fields[f[:i]] = f[i+1:]
+	}
+	return q, nil
+}
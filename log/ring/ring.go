@@ -0,0 +1,218 @@
+// Package ring keeps the last entries of a logger in memory, to assert them
+// in tests or serve them on a debug endpoint.
+package ring
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSize is the number of entries kept if the size is not positive.
+var DefaultSize = 1000
+
+// Entry is an entry kept with its fields, including the ones of With.
+type Entry struct {
+	Seq     uint64                 `json:"seq"`
+	Level   zapcore.Level          `json:"level"`
+	Time    time.Time              `json:"time"`
+	Logger  string                 `json:"logger,omitempty"`
+	Message string                 `json:"message"`
+	Caller  string                 `json:"caller,omitempty"`
+	Stack   string                 `json:"stack,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// Field returns the value of key formatted by fmt.Sprint.
+func (e *Entry) Field(key string) (string, bool) {
+	v, ok := e.Fields[key]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+// buffer is the ring shared by a Core and its With clones.
+type buffer struct {
+	mu      sync.RWMutex
+	entries []Entry
+	next    int // index written next
+	full    bool
+	seq     uint64
+}
+
+// Core is a zapcore.Core keeping the last entries of its level.
+type Core struct {
+	zapcore.LevelEnabler
+	buf     *buffer
+	context []zapcore.Field
+}
+
+// New returns a core keeping the last size entries enabled by enab.
+func New(size int, enab zapcore.LevelEnabler) *Core {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	return &Core{
+		LevelEnabler: enab,
+		buf:          &buffer{entries: make([]Entry, size)},
+	}
+}
+
+// NewLogger returns a logger writing to a core keeping the last entries of
+// every level, for tests.
+func NewLogger(size int) (*zap.Logger, *Core) {
+	c := New(size, zapcore.DebugLevel)
+	return zap.New(c), c
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	context := make([]zapcore.Field, 0, len(c.context)+len(fields))
+	context = append(context, c.context...)
+	context = append(context, fields...)
+	return &Core{c.LevelEnabler, c.buf, context}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core, the fields are encoded as they are now.
+func (c *Core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := Entry{
+		Level:   ent.Level,
+		Time:    ent.Time,
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+		Stack:   ent.Stack,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	if len(c.context)+len(fields) != 0 {
+		enc := zapcore.NewMapObjectEncoder()
+		for _, f := range c.context {
+			f.AddTo(enc)
+		}
+		for _, f := range fields {
+			f.AddTo(enc)
+		}
+		e.Fields = enc.Fields
+	}
+
+	b := c.buf
+	b.mu.Lock()
+	b.seq++
+	e.Seq = b.seq
+	b.entries[b.next] = e
+	b.next++
+	if b.next == len(b.entries) {
+		b.next, b.full = 0, true
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *Core) Sync() error {
+	return nil
+}
+
+// Entries returns the kept entries, the oldest first.
+func (c *Core) Entries() []Entry {
+	return c.Query(nil)
+}
+
+// Len returns the number of kept entries.
+func (c *Core) Len() int {
+	b := c.buf
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.full {
+		return len(b.entries)
+	}
+	return b.next
+}
+
+// Reset drops the kept entries.
+func (c *Core) Reset() {
+	b := c.buf
+	b.mu.Lock()
+	for i := range b.entries {
+		b.entries[i] = Entry{}
+	}
+	b.next, b.full = 0, false
+	b.mu.Unlock()
+}
+
+// Query selects entries, every set condition has to match.
+type Query struct {
+	Level   string            // min level, every level if empty
+	Logger  string            // the logger and its children
+	Message string            // contained in the message
+	Fields  map[string]string // values formatted by fmt.Sprint
+	After   uint64            // Seq above
+	Limit   int               // the latest ones, all if 0
+}
+
+// Match reports whether e matches q.
+func (q *Query) Match(e *Entry) bool {
+	if e.Seq <= q.After {
+		return false
+	}
+	if q.Level != "" {
+		var lvl zapcore.Level
+		if lvl.UnmarshalText([]byte(q.Level)) == nil && e.Level < lvl {
+			return false
+		}
+	}
+	if q.Logger != "" && e.Logger != q.Logger && !strings.HasPrefix(e.Logger, q.Logger+".") {
+		return false
+	}
+	if q.Message != "" && !strings.Contains(e.Message, q.Message) {
+		return false
+	}
+	for key, value := range q.Fields {
+		if v, ok := e.Field(key); !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the kept entries matching q, the oldest first, all if q is
+// nil.
+func (c *Core) Query(q *Query) []Entry {
+	if q == nil {
+		q = &Query{}
+	}
+
+	b := c.buf
+	b.mu.RLock()
+	var entries []Entry
+	n, start := b.next, 0
+	if b.full {
+		n, start = len(b.entries), b.next
+	}
+	for i := 0; i < n; i++ {
+		e := &b.entries[(start+i)%len(b.entries)]
+		if q.Match(e) {
+			entries = append(entries, *e)
+		}
+	}
+	b.mu.RUnlock()
+
+	if q.Limit > 0 && len(entries) > q.Limit {
+		entries = entries[len(entries)-q.Limit:]
+	}
+	return entries
+}
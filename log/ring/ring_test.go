@@ -0,0 +1,98 @@
+package ring
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func messages(entries []Entry) []string {
+	msgs := make([]string, len(entries))
+	for i, e := range entries {
+		msgs[i] = e.Message
+	}
+	return msgs
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCore(t *testing.T) {
+	l, c := NewLogger(4)
+	l.Debug("dropped")
+	l.Info("a", zap.Int("n", 1))
+	orders := l.Named("orders").With(zap.String("user", "bob"))
+	orders.Warn("b", zap.Int("n", 2))
+	l.Named("orders.matcher").Error("c")
+	l.Named("ordersx").Info("d")
+
+	if c.Len() != 4 {
+		t.Fatalf("len %d", c.Len())
+	}
+	if get := messages(c.Entries()); !equal(get, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("entries %v", get)
+	}
+
+	e := c.Entries()[1]
+	if e.Seq != 3 || e.Level != zapcore.WarnLevel || e.Logger != "orders" {
+		t.Errorf("entry %+v", e)
+	}
+	if v, ok := e.Field("user"); !ok || v != "bob" {
+		t.Errorf("user %q", v)
+	}
+
+	for _, tt := range []struct {
+		q        Query
+		expected []string
+	}{
+		{Query{Level: "warn"}, []string{"b", "c"}},
+		{Query{Logger: "orders"}, []string{"b", "c"}},
+		{Query{Message: "d"}, []string{"d"}},
+		{Query{Fields: map[string]string{"n": "2"}}, []string{"b"}},
+		{Query{After: 3}, []string{"c", "d"}},
+		{Query{Limit: 1}, []string{"d"}},
+	} {
+		if get := messages(c.Query(&tt.q)); !equal(get, tt.expected) {
+			t.Errorf("query %+v: %v", tt.q, get)
+		}
+	}
+
+	c.Reset()
+	if c.Len() != 0 || len(c.Entries()) != 0 {
+		t.Error("not reset")
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	l, c := NewLogger(10)
+	l.Info("a", zap.String("user", "bob"))
+	l.Warn("b", zap.String("user", "alice"))
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/?level=info&field=user=alice", nil))
+	var entries []Entry
+	if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Message != "b" || entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("entries %s", w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/?limit=x", nil))
+	if w.Code != 400 {
+		t.Errorf("code %d", w.Code)
+	}
+}
@@ -0,0 +1,527 @@
+// Package rotate implements a file writer rotated by size and time, whose
+// rotated files are compressed and removed in the background.
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Rotations
+const (
+	Hourly = "hourly"
+	Daily  = "daily"
+)
+
+// Compressions
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+const megabyte = 1024 * 1024
+
+var errClosed = errors.New("rotate: writer closed")
+
+// Config ..
+type Config struct {
+	Filename     string // file written
+	Pattern      string // of the rotated files, with %Y %m %d %H %M %S, default <Filename>.%Y%m%d-%H%M%S
+	MaxSize      int    // M, size rotation off if 0
+	Rotation     string // hourly/daily or a duration like 15m, time rotation off if empty
+	Compress     string // gzip/zstd, off if empty
+	MaxBackups   int    // rotated files kept, all if 0
+	MaxAge       int    // days a rotated file is kept since its start, forever if 0
+	MaxTotalSize int    // M of the rotated files, unlimited if 0
+	UTC          bool   // names and periods in UTC instead of the local time
+}
+
+// Clock returns the current time, the system clock by default.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// Option ..
+type Option func(*Writer)
+
+// WithClock sets the clock of the rotations and retention.
+func WithClock(clock Clock) Option {
+	return func(w *Writer) {
+		w.clock = clock
+	}
+}
+
+// Writer writes to Filename, renaming it by Pattern once MaxSize or the
+// end of the Rotation period is reached. The rotated files are compressed
+// and the ones over the retention removed by a background goroutine.
+type Writer struct {
+	config   Config
+	clock    Clock
+	maxSize  int64
+	period   time.Duration // of durations, hourly and daily follow the calendar
+	pattern  *pattern
+	compress string
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	next     time.Time // of the time rotation
+	closed   bool
+
+	mill     chan struct{}
+	millDone chan struct{}
+}
+
+// New ..
+func New(config *Config, opts ...Option) (*Writer, error) {
+	if config.Filename == "" {
+		return nil, errors.New("rotate: no filename")
+	}
+
+	w := &Writer{
+		config:   *config,
+		clock:    systemClock{},
+		maxSize:  int64(config.MaxSize) * megabyte,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(w)
+	}
+
+	switch config.Rotation {
+	case "", Hourly, Daily:
+	default:
+		d, err := time.ParseDuration(config.Rotation)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("rotate: bad rotation %q", config.Rotation)
+		}
+		w.period = d
+	}
+
+	switch config.Compress {
+	case "", Gzip, Zstd:
+		w.compress = config.Compress
+	default:
+		return nil, fmt.Errorf("rotate: unknown compression %q", config.Compress)
+	}
+
+	p := config.Pattern
+	if p == "" {
+		p = config.Filename + ".%Y%m%d-%H%M%S"
+	} else if !filepath.IsAbs(p) && filepath.Dir(p) == "." {
+		p = filepath.Join(filepath.Dir(config.Filename), p)
+	}
+	var err error
+	if w.pattern, err = newPattern(p); err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(config.Filename), 0755); err != nil {
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+func (w *Writer) now() time.Time {
+	if w.config.UTC {
+		return w.clock.Now().UTC()
+	}
+	return w.clock.Now()
+}
+
+// nextRotation returns the end of the period of t.
+func (w *Writer) nextRotation(t time.Time) time.Time {
+	switch w.config.Rotation {
+	case "":
+		return time.Time{}
+	case Hourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+	case Daily:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(w.period).Add(w.period)
+}
+
+// Write implements io.Writer.
+func (w *Writer) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errClosed
+	}
+	if w.file == nil {
+		if err = w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	now := w.now()
+	if (!w.next.IsZero() && !now.Before(w.next)) ||
+		(w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize) {
+		if err = w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// open opens Filename, rotating it first if it belongs to a past period.
+func (w *Writer) open() error {
+	now := w.now()
+	info, err := os.Stat(w.config.Filename)
+	if os.IsNotExist(err) {
+		return w.create(now)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.openedAt = info.ModTime().In(now.Location())
+	if next := w.nextRotation(w.openedAt); !next.IsZero() && !now.Before(next) {
+		if err = w.rename(); err != nil {
+			return err
+		}
+		return w.create(now)
+	}
+
+	f, err := os.OpenFile(w.config.Filename, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	w.next = w.nextRotation(w.openedAt)
+	return nil
+}
+
+func (w *Writer) create(now time.Time) error {
+	f, err := os.OpenFile(w.config.Filename, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.size, w.openedAt = f, 0, now
+	w.next = w.nextRotation(now)
+	return nil
+}
+
+// rename renames Filename by the pattern and the time it was opened.
+func (w *Writer) rename() error {
+	name := w.pattern.format(w.openedAt)
+	for i := 1; exists(name) || exists(name+".gz") || exists(name+".zst"); i++ {
+		name = w.pattern.format(w.openedAt) + "." + strconv.Itoa(i)
+	}
+	if err := os.Rename(w.config.Filename, name); err != nil {
+		return err
+	}
+
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *Writer) rotate(now time.Time) error {
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	if err := w.rename(); err != nil {
+		return err
+	}
+	return w.create(now)
+}
+
+// Rotate rotates the file now.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	return w.rotate(w.now())
+}
+
+// Sync commits the file to the disk.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file and waits for the rotated files to be compressed
+// and removed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	close(w.mill)
+	w.mu.Unlock()
+
+	<-w.millDone
+	return err
+}
+
+// run compresses and removes the rotated files after each rotation.
+func (w *Writer) run() {
+	defer close(w.millDone)
+	for range w.mill {
+		if err := w.millRun(); err != nil {
+			fmt.Fprintf(os.Stderr, "[log] rotate %s err: %v\n", w.config.Filename, err)
+		}
+	}
+}
+
+// backup is a rotated file.
+type backup struct {
+	name  string
+	t     time.Time
+	index int
+	size  int64
+	ext   string // of the compression
+}
+
+// backups returns the rotated files, the latest first.
+func (w *Writer) backups() ([]*backup, error) {
+	dir := filepath.Dir(w.pattern.path)
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []*backup
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		b := w.pattern.parse(info.Name(), w.now().Location())
+		if b == nil {
+			continue
+		}
+		b.name, b.size = filepath.Join(dir, info.Name()), info.Size()
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].t.Equal(backups[j].t) {
+			return backups[i].t.After(backups[j].t)
+		}
+		return backups[i].index > backups[j].index
+	})
+	return backups, nil
+}
+
+func (w *Writer) millRun() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	if w.compress != "" {
+		for _, b := range backups {
+			if b.ext != "" {
+				continue
+			}
+			if err := compressFile(b.name, w.compress); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		if backups, err = w.backups(); err != nil {
+			return err
+		}
+	}
+
+	var (
+		total  int64
+		over   bool // of MaxTotalSize, the older files are removed too
+		cutoff time.Time
+	)
+	if w.config.MaxAge > 0 {
+		cutoff = w.now().Add(-time.Duration(w.config.MaxAge) * 24 * time.Hour)
+	}
+	for i, b := range backups {
+		total += b.size
+		over = over || (w.config.MaxTotalSize > 0 && total > int64(w.config.MaxTotalSize)*megabyte)
+		remove := over || (w.config.MaxBackups > 0 && i >= w.config.MaxBackups) ||
+			(!cutoff.IsZero() && b.t.Before(cutoff))
+		if !remove {
+			continue
+		}
+		if err := os.Remove(b.name); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// compressFile compresses name into name.gz or name.zst, removing name.
+func compressFile(name, compression string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	ext := ".gz"
+	if compression == Zstd {
+		ext = ".zst"
+	}
+	tmp := name + ext + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	var zw io.WriteCloser
+	if compression == Zstd {
+		if zw, err = zstd.NewWriter(dst); err != nil {
+			return err
+		}
+	} else {
+		zw = gzip.NewWriter(dst)
+	}
+	if _, err = io.Copy(zw, src); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, name+ext); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// pattern formats and parses the names of the rotated files.
+type pattern struct {
+	path   string
+	layout string // time layout of the tokens, in order
+	re     *regexp.Regexp
+}
+
+var patternTokens = map[byte]string{
+	'Y': "2006", 'm': "01", 'd': "02", 'H': "15", 'M': "04", 'S': "05",
+}
+
+func newPattern(path string) (*pattern, error) {
+	if strings.IndexByte(filepath.Dir(path), '%') >= 0 {
+		return nil, fmt.Errorf("rotate: pattern %s: the directory can't vary", path)
+	}
+
+	p := &pattern{path: path}
+	base := filepath.Base(path)
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(base); i++ {
+		if base[i] == '%' && i+1 < len(base) {
+			if layout, ok := patternTokens[base[i+1]]; ok {
+				p.layout += layout
+				fmt.Fprintf(&re, `(\d{%d})`, len(layout))
+				i++
+				continue
+			}
+		}
+		re.WriteString(regexp.QuoteMeta(base[i : i+1]))
+	}
+	re.WriteString(`(?:\.(\d+))?(\.gz|\.zst)?$`)
+	p.re = regexp.MustCompile(re.String())
+	return p, nil
+}
+
+func (p *pattern) format(t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(p.path); i++ {
+		if p.path[i] == '%' && i+1 < len(p.path) {
+			if layout, ok := patternTokens[p.path[i+1]]; ok {
+				b.WriteString(t.Format(layout))
+				i++
+				continue
+			}
+		}
+		b.WriteByte(p.path[i])
+	}
+	return b.String()
+}
+
+// parse returns the backup of a file name, nil if it is not one.
+func (p *pattern) parse(name string, loc *time.Location) *backup {
+	m := p.re.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+
+	n := len(m)
+	b := &backup{ext: m[n-1]}
+	if m[n-2] != "" {
+		b.index, _ = strconv.Atoi(m[n-2])
+	}
+	if p.layout != "" {
+		t, err := time.ParseInLocation(p.layout, strings.Join(m[1:n-2], ""), loc)
+		if err != nil {
+			return nil
+		}
+		b.t = t
+	}
+	return b
+}
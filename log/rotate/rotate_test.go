@@ -0,0 +1,277 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) add(d time.Duration) { c.t = c.t.Add(d) }
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func files(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func read(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRotateSizeAndTime(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{time.Date(2020, 1, 1, 10, 30, 0, 0, time.UTC)}
+	w, err := New(&Config{
+		Filename: filepath.Join(dir, "app.log"),
+		Pattern:  "app.%Y%m%d%H.log",
+		Rotation: Hourly,
+		UTC:      true,
+	}, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.maxSize = 10
+
+	w.Write([]byte("12345\n"))
+	w.Write([]byte("67890\n")) // over the size
+	clock.add(40 * time.Minute)
+	w.Write([]byte("next\n")) // the next hour
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"app.2020010110.log", "app.2020010110.log.1", "app.log"}
+	if get := files(t, dir); !equal(get, expected) {
+		t.Fatalf("files %v", get)
+	}
+	for name, content := range map[string]string{
+		"app.2020010110.log":   "12345\n",
+		"app.2020010110.log.1": "67890\n",
+		"app.log":              "next\n",
+	} {
+		if get := read(t, filepath.Join(dir, name)); get != content {
+			t.Errorf("%s: %q", name, get)
+		}
+	}
+}
+
+func TestRotateDaily(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// periods follow the midnight of the clock location unless UTC
+	for _, utc := range []bool{false, true} {
+		clock := &fakeClock{time.Date(2020, 1, 1, 23, 59, 0, 0, time.FixedZone("CST", 8*3600))}
+		w, err := New(&Config{
+			Filename: filepath.Join(dir, "app.log"),
+			Pattern:  "app.log.%Y%m%d",
+			Rotation: Daily,
+			UTC:      utc,
+		}, WithClock(clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("a\n"))
+		clock.add(time.Minute)
+		w.Write([]byte("b\n"))
+		w.Close()
+
+		expected := []string{"app.log", "app.log.20200101"}
+		if utc {
+			expected = []string{"app.log"}
+		}
+		if get := files(t, dir); !equal(get, expected) {
+			t.Fatalf("utc %v files %v", utc, get)
+		}
+		os.Remove(filepath.Join(dir, "app.log"))
+		os.Remove(filepath.Join(dir, "app.log.20200101"))
+	}
+}
+
+func TestCompress(t *testing.T) {
+	for _, compression := range []string{Gzip, Zstd} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		clock := &fakeClock{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+		w, err := New(&Config{
+			Filename: filepath.Join(dir, "app.log"),
+			Compress: compression,
+			UTC:      true,
+		}, WithClock(clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("hello\n"))
+		if err = w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+
+		ext := ".gz"
+		if compression == Zstd {
+			ext = ".zst"
+		}
+		name := "app.log.20200101-000000" + ext
+		if get := files(t, dir); !equal(get, []string{"app.log", name}) {
+			t.Fatalf("%s files %v", compression, get)
+		}
+
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data []byte
+		if compression == Zstd {
+			d, _ := zstd.NewReader(f)
+			data, err = ioutil.ReadAll(d)
+			d.Close()
+		} else {
+			r, _ := gzip.NewReader(f)
+			data, err = ioutil.ReadAll(r)
+		}
+		f.Close()
+		if err != nil || string(data) != "hello\n" {
+			t.Errorf("%s content %q %v", compression, data, err)
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	clock := &fakeClock{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+	config := &Config{
+		Filename:   filepath.Join(dir, "app.log"),
+		Pattern:    "app.%Y%m%d.log",
+		Rotation:   Daily,
+		MaxBackups: 3,
+		MaxAge:     2,
+		UTC:        true,
+	}
+	w, err := New(config, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		w.Write([]byte("line\n"))
+		clock.add(24 * time.Hour)
+	}
+	w.Write([]byte("line\n"))
+	w.Close()
+
+	// 5 days rotated, the ones started over 2 days ago removed
+	expected := []string{"app.20200104.log", "app.20200105.log", "app.log"}
+	if get := files(t, dir); !equal(get, expected) {
+		t.Fatalf("age files %v", get)
+	}
+
+	// the current file is reopened as written at the clock time
+	touch := func() {
+		if err := os.Chtimes(config.Filename, clock.t, clock.t); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// by count
+	touch()
+	config.MaxAge = 0
+	config.MaxBackups = 1
+	if w, err = New(config, WithClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	clock.add(24 * time.Hour)
+	w.Write([]byte("line\n"))
+	w.Close()
+	expected = []string{"app.20200106.log", "app.log"}
+	if get := files(t, dir); !equal(get, expected) {
+		t.Fatalf("count files %v", get)
+	}
+
+	// by total size, the files older than the first over it are removed too
+	touch()
+	config.MaxBackups = 0
+	config.MaxTotalSize = 1
+	if w, err = New(config, WithClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	big := make([]byte, megabyte/2-100)
+	for i := 0; i < 3; i++ {
+		w.Write(big)
+		clock.add(24 * time.Hour)
+	}
+	w.Write([]byte("line\n"))
+	w.Close()
+	expected = []string{"app.20200108.log", "app.20200109.log", "app.log"}
+	if get := files(t, dir); !equal(get, expected) {
+		t.Fatalf("size files %v", get)
+	}
+}
+
+func TestPattern(t *testing.T) {
+	p, err := newPattern("/var/log/app-%Y-%m-%d.%H%M%S.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	name := p.format(tm)
+	if name != "/var/log/app-2020-03-04.050607.log" {
+		t.Fatalf("format %s", name)
+	}
+	b := p.parse(filepath.Base(name)+".2.zst", time.UTC)
+	if b == nil || !b.t.Equal(tm) || b.index != 2 || b.ext != ".zst" {
+		t.Errorf("parse %+v", b)
+	}
+	if p.parse("app.log", time.UTC) != nil {
+		t.Error("parsed the current file")
+	}
+	if _, err = newPattern("/var/%Y/app.log"); err == nil {
+		t.Error("varying directory")
+	}
+}
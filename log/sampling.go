@@ -0,0 +1,90 @@
+package log
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingReportInterval is the interval the dropped entries are logged at.
+var SamplingReportInterval = time.Minute
+
+// sampleCore drops the entries of the main core over its sampling config.
+type sampleCore struct {
+	zapcore.Core
+	sampler *hook.Sampler
+}
+
+func (c *sampleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sampleCore{c.Core.With(fields), c.sampler}
+}
+
+func (c *sampleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) || !c.sampler.Allow(ent) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// reportDrops logs the entries dropped by the samplers and the async
+// writers every interval
+// until the cores are closed, shutdown logs the last ones.
+func (s *coreSet) reportDrops(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-t.C:
+			s.writeDrops()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeDrops writes an entry per sampled core with the dropped entries per
+// level to the main core, whatever its sampling.
+func (s *coreSet) writeDrops() {
+	names := make([]string, 0, len(s.samplers))
+	for name := range s.samplers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		drops := s.samplers[name].Drops()
+		if len(drops) == 0 {
+			continue
+		}
+
+		fields := []zapcore.Field{zap.String("core", name)}
+		for _, lvl := range hook.AllLevels {
+			if n, ok := drops[lvl]; ok {
+				fields = append(fields, zap.Uint64(lvl.String(), n))
+			}
+		}
+		s.main.Write(zapcore.Entry{
+			Level:   zapcore.WarnLevel,
+			Time:    time.Now(),
+			Message: "log entries dropped by sampling",
+		}, fields)
+	}
+
+	var dropped uint64
+	for _, w := range s.asyncs {
+		n := w.Dropped()
+		dropped += n - atomic.SwapUint64(&w.reported, n)
+	}
+	if dropped != 0 {
+		s.main.Write(zapcore.Entry{
+			Level:   zapcore.WarnLevel,
+			Time:    time.Now(),
+			Message: "log entries dropped by the async writer",
+		}, []zapcore.Field{zap.Uint64("dropped", dropped)})
+	}
+}
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hkjojo/go-toolkits/log/hook"
+)
+
+func TestSampling(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	logger, err := New(&Config{
+		Path:          path,
+		MaxSize:       1,
+		Format:        "json",
+		DisableStdout: true,
+		Sampling: &hook.SamplingConfig{
+			Initial:    2,
+			Thereafter: 3,
+			Rate:       map[string]int{"warn": 1},
+			Burst:      map[string]int{"warn": 2},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Info("sampled")
+		logger.Warn("limited")
+	}
+	logger.Close(context.Background())
+
+	data, _ := ioutil.ReadFile(path)
+	if n := strings.Count(string(data), `"msg":"sampled"`); n != 4 {
+		t.Fatalf("expected 4 sampled entries, got %d\n%s", n, data)
+	}
+	if n := strings.Count(string(data), `"msg":"limited"`); n != 2 {
+		t.Fatalf("expected 2 limited entries, got %d\n%s", n, data)
+	}
+	if !strings.Contains(string(data), `"msg":"log entries dropped by sampling","core":"main","info":6,"warn":8`) {
+		t.Fatalf("expected dropped entries report\n%s", data)
+	}
+}
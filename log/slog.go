@@ -0,0 +1,155 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slogHandler is a slog.Handler writing to the cores of a logger, the
+// groups are namespaces of the fields.
+type slogHandler struct {
+	core   zapcore.Core
+	caller bool
+}
+
+// SlogHandler returns a slog.Handler writing through the cores, hooks and
+// redaction of the logger, with the fields of the context extractors.
+//
+//	slog.SetDefault(slog.New(logger.SlogHandler()))
+func (log *Logger) SlogHandler() slog.Handler {
+	return &slogHandler{core: log.Core(), caller: log.config.Caller}
+}
+
+// SlogHandler returns the slog.Handler of the standard logger, see
+// Logger.SlogHandler. It is not switched by a later Init.
+func SlogHandler() slog.Handler {
+	return logger.SlogHandler()
+}
+
+// slogLevel maps the slog levels to zap, the ones above error are errors.
+func slogLevel(lvl slog.Level) zapcore.Level {
+	switch {
+	case lvl < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case lvl < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case lvl < slog.LevelError:
+		return zapcore.WarnLevel
+	}
+	return zapcore.ErrorLevel
+}
+
+func (h *slogHandler) Enabled(_ context.Context, lvl slog.Level) bool {
+	return h.core.Enabled(slogLevel(lvl))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		Level:   slogLevel(r.Level),
+		Time:    r.Time,
+		Message: r.Message,
+	}
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+	if h.caller && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ce.Entry.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+	}
+
+	fields := make([]zapcore.Field, 0, r.NumAttrs())
+	if ctx != nil {
+		kvs := contextFields(ctx)
+		for i := 0; i+1 < len(kvs); i += 2 {
+			if key, ok := kvs[i].(string); ok {
+				fields = append(fields, zap.Any(key, kvs[i+1]))
+			}
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, a)
+		return true
+	})
+	ce.Write(fields...)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zapcore.Field
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	clone := *h
+	clone.core = h.core.With(fields)
+	return &clone
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.core = h.core.With([]zapcore.Field{zap.Namespace(name)})
+	return &clone
+}
+
+// appendSlogAttr appends the field of a, the attrs of a group without key
+// are inlined.
+func appendSlogAttr(fields []zapcore.Field, a slog.Attr) []zapcore.Field {
+	v := a.Value.Resolve()
+	if a.Key == "" && v.Kind() != slog.KindGroup {
+		return fields
+	}
+
+	switch v.Kind() {
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, a := range attrs {
+				fields = appendSlogAttr(fields, a)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+	}
+
+	if err, ok := v.Any().(error); ok {
+		return append(fields, zap.NamedError(a.Key, err))
+	}
+	return append(fields, zap.Any(a.Key, v.Any()))
+}
+
+// slogGroup marshals the attrs of a group as an object.
+type slogGroup []slog.Attr
+
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range appendSlogAttr(nil, slog.Any("", slog.GroupValue(g...))) {
+		f.AddTo(enc)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, recent := newRingLogger(t)
+
+	s := slog.New(l.SlogHandler())
+	s.With("a", 1).WithGroup("g").Info("hi", "b", 2, slog.Group("h", "c", 3))
+	s.Log(context.Background(), slog.LevelError+4, "bad", "err", errors.New("boom"), slog.Group("", "inline", true))
+	s.Debug("debug")
+
+	entries := recent.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("entries %+v", entries)
+	}
+	if e := entries[0]; e.Level != zapcore.InfoLevel || e.Message != "hi" ||
+		fmt.Sprint(e.Fields) != "map[a:1 g:map[b:2 h:map[c:3]]]" {
+		t.Errorf("entry %+v", e)
+	}
+	if e := entries[1]; e.Level != zapcore.ErrorLevel || fmt.Sprint(e.Fields) != "map[err:boom inline:true]" {
+		t.Errorf("entry %+v", e)
+	}
+	if e := entries[2]; e.Level != zapcore.DebugLevel {
+		t.Errorf("entry %+v", e)
+	}
+}
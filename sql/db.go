@@ -29,9 +29,16 @@ func Goqu() *goqu.Database {
 	return DefaultDB.Goqu()
 }
 
+// Logger logs the queries of gorm when Config.Debug is set, e.g. the
+// log.GormLogger of github.com/hkjojo/go-toolkits/log.
+type Logger interface {
+	Print(v ...interface{})
+}
+
 // Config ..
 type Config struct {
 	Debug           bool
+	Logger          Logger // default gorm stdout logger
 	Dialect         string
 	URL             string
 	MaxOpenConns    int
@@ -60,6 +67,9 @@ func Open(cfg *Config) (*DataBase, error) {
 	db.SingularTable(true)
 	if cfg.Debug {
 		db.LogMode(true)
+		if cfg.Logger != nil {
+			db.SetLogger(cfg.Logger)
+		}
 	}
 
 	if cfg.MaxOpenConns != 0 {
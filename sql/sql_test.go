@@ -16,3 +16,23 @@ func TestGetGormRows(t *testing.T) {
 		t.Fail()
 	}
 }
+
+type printLogger [][]interface{}
+
+func (p *printLogger) Print(v ...interface{}) {
+	*p = append(*p, v)
+}
+
+func TestOpenLogger(t *testing.T) {
+	var p printLogger
+	db, err := Open(&Config{Dialect: "sqlite3", URL: ":memory:", Debug: true, Logger: &p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.Exec("SELECT 1")
+	if len(p) != 1 || p[0][0] != "sql" || p[0][3] != "SELECT 1" {
+		t.Errorf("logged %v", p)
+	}
+}
@@ -0,0 +1,83 @@
+package wsrpc
+
+/*
+	JSON admin api presented at http://machine:port/debug/rpc/conns
+
+	GET    /debug/rpc/conns                     list live connections
+	GET    /debug/rpc/conns?id=1                inspect one connection
+	DELETE /debug/rpc/conns?id=1&reason=spam    disconnect one connection
+*/
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// DefaultAdminPath used by AdminHandler
+const DefaultAdminPath = "/debug/rpc/conns"
+
+type adminHTTP struct {
+	*Server
+}
+
+// AdminHandler returns the connection admin api handler.
+func (server *Server) AdminHandler() http.Handler {
+	return adminHTTP{server}
+}
+
+// Runs at /debug/rpc/conns
+func (server adminHTTP) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	if query.Get("id") == "" {
+		if req.Method != http.MethodGet {
+			writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		conns := server.Conns()
+		infos := make([]*ConnInfo, 0, len(conns))
+		for _, conn := range conns {
+			infos = append(infos, conn.Info())
+		}
+		writeAdminJSON(w, http.StatusOK, infos)
+		return
+	}
+
+	id, err := strconv.ParseUint(query.Get("id"), 10, 64)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, "invalid id: "+query.Get("id"))
+		return
+	}
+
+	conn := server.Conn(id)
+	if conn == nil {
+		writeAdminError(w, http.StatusNotFound, "conn not found: "+query.Get("id"))
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		writeAdminJSON(w, http.StatusOK, conn.Info())
+	case http.MethodDelete, http.MethodPost:
+		reason := query.Get("reason")
+		if reason == "" {
+			reason = "disconnected by admin"
+		}
+		info := conn.Info()
+		conn.Disconnect(reason)
+		writeAdminJSON(w, http.StatusOK, info)
+	default:
+		writeAdminError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, code int, msg string) {
+	writeAdminJSON(w, code, map[string]string{"error": msg})
+}
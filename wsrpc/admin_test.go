@@ -0,0 +1,99 @@
+package wsrpc_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+	"github.com/hkjojo/go-toolkits/wsrpc/wsrpctest"
+)
+
+type Ping struct{}
+
+func (p *Ping) Ping(conn *wsrpc.Conn, req *string, rsp *string) error {
+	*rsp = "pong"
+	return nil
+}
+
+func TestAdminHandler(t *testing.T) {
+	srv := wsrpc.NewServer()
+	srv.RegisterName("Ping", &Ping{})
+
+	c := wsrpctest.NewClient(srv, wsrpctest.WithOnInit(func(conn *wsrpc.Conn) {
+		conn.SetPrincipal("bob")
+		conn.AddTopic("ticker")
+	}))
+	defer c.Close()
+
+	if err := c.Call("Ping.Ping", "ping", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the response is read before the server has accounted for it
+	eventually(t, func() bool {
+		conn := srv.Conns()[0]
+		_, out := conn.Bytes()
+		return conn.InFlight() == 0 && out != 0
+	})
+
+	admin := srv.AdminHandler()
+	do := func(method, target string, v interface{}) int {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code
+	}
+
+	var infos []*wsrpc.ConnInfo
+	if code := do(http.MethodGet, wsrpc.DefaultAdminPath, &infos); code != http.StatusOK {
+		t.Fatalf("expected:%d get:%d", http.StatusOK, code)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected:1 conn get:%d", len(infos))
+	}
+	info := infos[0]
+	if info.Principal != "bob" || len(info.Topics) != 1 || info.Topics[0] != "ticker" {
+		t.Fatalf("unexpected conn info %+v", info)
+	}
+	if info.BytesIn == 0 || info.BytesOut == 0 || info.InFlight != 0 {
+		t.Fatalf("unexpected conn counters %+v", info)
+	}
+
+	target := wsrpc.DefaultAdminPath + "?id=" + strconv.FormatUint(info.ID, 10)
+	if code := do(http.MethodGet, target, info); code != http.StatusOK {
+		t.Fatalf("expected:%d get:%d", http.StatusOK, code)
+	}
+
+	var rsp map[string]string
+	if code := do(http.MethodGet, wsrpc.DefaultAdminPath+"?id=100", &rsp); code != http.StatusNotFound {
+		t.Fatalf("expected:%d get:%d", http.StatusNotFound, code)
+	}
+
+	if code := do(http.MethodDelete, target+"&reason=spam", info); code != http.StatusOK {
+		t.Fatalf("expected:%d get:%d", http.StatusOK, code)
+	}
+	n := c.ExpectNotification(t, wsrpc.DisconnectMethod, time.Second)
+	if err := n.Decode(&rsp); err != nil || rsp["reason"] != "spam" {
+		t.Fatalf("unexpected disconnect params %s", n.Params)
+	}
+
+	eventually(t, func() bool { return len(srv.Conns()) == 0 })
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -9,11 +9,13 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
 )
 
 var errMissingParams = errors.New("jsonrpc: request body missing params")
 
 type serverCodec struct {
+	rwc *countingConn
 	dec *json.Decoder // for reading JSON values
 	enc *json.Encoder // for writing JSON values
 	c   io.Closer
@@ -34,14 +36,40 @@ type serverCodec struct {
 
 // NewServerCodec returns a new ServerCodec using JSON-RPC on conn.
 func NewServerCodec(conn io.ReadWriteCloser) ServerCodec {
+	rwc := &countingConn{ReadWriteCloser: conn}
 	return &serverCodec{
-		dec:     json.NewDecoder(conn),
-		enc:     json.NewEncoder(conn),
-		c:       conn,
+		rwc:     rwc,
+		dec:     json.NewDecoder(rwc),
+		enc:     json.NewEncoder(rwc),
+		c:       rwc,
 		pending: make(map[uint64]*json.RawMessage),
 	}
 }
 
+// countingConn counts the bytes read from and written to conn.
+type countingConn struct {
+	in  uint64
+	out uint64
+	io.ReadWriteCloser
+}
+
+func (c *countingConn) Read(p []byte) (n int, err error) {
+	n, err = c.ReadWriteCloser.Read(p)
+	atomic.AddUint64(&c.in, uint64(n))
+	return
+}
+
+func (c *countingConn) Write(p []byte) (n int, err error) {
+	n, err = c.ReadWriteCloser.Write(p)
+	atomic.AddUint64(&c.out, uint64(n))
+	return
+}
+
+// Bytes returns bytes read from and written to the connection.
+func (c *serverCodec) Bytes() (in, out uint64) {
+	return atomic.LoadUint64(&c.rwc.in), atomic.LoadUint64(&c.rwc.out)
+}
+
 type serverRequest struct {
 	Version string           `json:"jsonrpc"`
 	Method  string           `json:"method"`
@@ -115,9 +143,24 @@ func (c *serverCodec) ReadRequestBody(x interface{}) error {
 
 // GetParams ...
 func (c *serverCodec) GetParams() json.RawMessage {
+	if c.req.Params == nil {
+		return nil
+	}
 	return *c.req.Params
 }
 
+// RequestID returns the JSON id of the pending request seq.
+func (c *serverCodec) RequestID(seq uint64) json.RawMessage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	b := c.pending[seq]
+	if b == nil {
+		return nil
+	}
+	return *b
+}
+
 // GetMethod
 func (c *serverCodec) GetMethod() string {
 	return c.req.Method
@@ -1,11 +1,18 @@
 package wsrpc
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"net/http"
 )
 
+// DisconnectMethod is the notification sent to a client before it is
+// disconnected by Disconnect.
+const DisconnectMethod = "rpc.disconnect"
+
 type notifyEvent struct {
 	method string
 	params interface{}
@@ -16,6 +23,8 @@ type ConnCloseHandler func()
 
 // Conn ...
 type Conn struct {
+	inflight int64 // accessed atomically, keep 64-bit aligned
+
 	Request       *http.Request
 	rwc           *ReadWriteCloser
 	codec         ServerCodec
@@ -24,15 +33,40 @@ type Conn struct {
 	mu            sync.RWMutex
 	closeHandlers []ConnCloseHandler
 	extraData     map[string]interface{}
+
+	id          uint64
+	connectedAt time.Time
+	principal   string
+	topics      map[string]struct{}
+	streams     map[string]*Stream
+}
+
+// ConnInfo is a snapshot of a connection state, as served by the admin api.
+type ConnInfo struct {
+	ID          uint64    `json:"id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	Principal   string    `json:"principal"`
+	Topics      []string  `json:"topics"`
+	InFlight    int64     `json:"in_flight"`
+	BytesIn     uint64    `json:"bytes_in"`
+	BytesOut    uint64    `json:"bytes_out"`
+}
+
+type byteCounter interface {
+	Bytes() (in, out uint64)
 }
 
 // NewConn ...
 func NewConn(req *http.Request, sending *sync.Mutex, codec ServerCodec) *Conn {
 	conn := &Conn{
-		Request:   req,
-		sending:   sending,
-		codec:     codec,
-		extraData: make(map[string]interface{}),
+		Request:     req,
+		sending:     sending,
+		codec:       codec,
+		extraData:   make(map[string]interface{}),
+		connectedAt: time.Now(),
+		topics:      make(map[string]struct{}),
+		streams:     make(map[string]*Stream),
 	}
 
 	return conn
@@ -41,6 +75,9 @@ func NewConn(req *http.Request, sending *sync.Mutex, codec ServerCodec) *Conn {
 func (c *Conn) ternimating() {
 	c.mu.Lock()
 	c.closed = true
+	for _, stream := range c.streams {
+		stream.close()
+	}
 	c.mu.Unlock()
 
 	for _, handler := range c.closeHandlers {
@@ -105,3 +142,102 @@ func (c *Conn) DelData(key string) {
 
 	delete(c.extraData, key)
 }
+
+// ID returns the id the server assigned to the connection.
+func (c *Conn) ID() uint64 {
+	return c.id
+}
+
+// ConnectedAt ...
+func (c *Conn) ConnectedAt() time.Time {
+	return c.connectedAt
+}
+
+// RemoteAddr ...
+func (c *Conn) RemoteAddr() string {
+	if c.Request == nil {
+		return ""
+	}
+	return c.Request.RemoteAddr
+}
+
+// SetPrincipal records who is authenticated on the connection,
+// usually called by a login handler.
+func (c *Conn) SetPrincipal(principal string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.principal = principal
+}
+
+// Principal ...
+func (c *Conn) Principal() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.principal
+}
+
+// AddTopic records a topic the connection subscribed to.
+func (c *Conn) AddTopic(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.topics[topic] = struct{}{}
+}
+
+// RemoveTopic ...
+func (c *Conn) RemoveTopic(topic string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.topics, topic)
+}
+
+// Topics returns the sorted subscribed topics.
+func (c *Conn) Topics() []string {
+	c.mu.RLock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(topics)
+	return topics
+}
+
+// InFlight returns the number of calls being handled.
+func (c *Conn) InFlight() int64 {
+	return atomic.LoadInt64(&c.inflight)
+}
+
+// Bytes returns bytes read from and written to the connection, zero if the
+// codec does not count them.
+func (c *Conn) Bytes() (in, out uint64) {
+	if counter, ok := c.codec.(byteCounter); ok {
+		return counter.Bytes()
+	}
+	return 0, 0
+}
+
+// Info ...
+func (c *Conn) Info() *ConnInfo {
+	in, out := c.Bytes()
+	return &ConnInfo{
+		ID:          c.id,
+		RemoteAddr:  c.RemoteAddr(),
+		ConnectedAt: c.connectedAt,
+		Principal:   c.Principal(),
+		Topics:      c.Topics(),
+		InFlight:    c.InFlight(),
+		BytesIn:     in,
+		BytesOut:    out,
+	}
+}
+
+// Disconnect notifies the client with the reason then closes the connection.
+func (c *Conn) Disconnect(reason string) error {
+	c.NotifyEx(DisconnectMethod, map[string]string{"reason": reason})
+	return c.Close()
+}
@@ -3,6 +3,7 @@ package wsrpc
 import (
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -13,6 +14,7 @@ type ReadWriteCloser struct {
 	WS   *websocket.Conn
 	r    io.Reader
 	done chan struct{}
+	once sync.Once
 }
 
 // NewReadWriteCloser ...
@@ -94,8 +96,10 @@ func (rwc *ReadWriteCloser) Write(p []byte) (n int, err error) {
 
 // Close ...
 func (rwc *ReadWriteCloser) Close() (err error) {
+	// Close is called by both Conn.Close and ServeCodec, only the first
+	// call may signal the ping loop
 	err = rwc.WS.Close()
-	rwc.done <- struct{}{}
+	rwc.once.Do(func() { close(rwc.done) })
 	return
 }
 
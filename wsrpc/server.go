@@ -133,8 +133,10 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"unicode"
 	"unicode/utf8"
 )
@@ -149,6 +151,7 @@ const (
 // because Typeof takes an empty interface value. This is annoying.
 var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
 var typeOfConn = reflect.TypeOf(&Conn{})
+var typeOfStream = reflect.TypeOf(&Stream{})
 
 type methodType struct {
 	sync.Mutex // protects counters
@@ -156,6 +159,7 @@ type methodType struct {
 	ArgType    reflect.Type
 	ReplyType  reflect.Type
 	numCalls   uint
+	stream     bool // reply is a *Stream
 }
 
 type service struct {
@@ -210,6 +214,10 @@ type Server struct {
 	freeReq    *Request
 	respLock   sync.Mutex // protects freeResp
 	freeResp   *Response
+	connLock   sync.RWMutex // protects conns, connSeq
+	conns      map[uint64]*Conn
+	connSeq    uint64
+	window     int // initial credits of streams
 
 	onConnInit      ConnHandler
 	onMissingMethod MissingMethodFunc
@@ -230,7 +238,10 @@ func (err ErrMissingServiceMethod) Error() string {
 
 // NewServer returns a new Server.
 func NewServer() *Server {
-	return &Server{serviceMap: make(map[string]*service)}
+	return &Server{
+		serviceMap: make(map[string]*service),
+		conns:      make(map[uint64]*Conn),
+	}
 }
 
 // DefaultServer is the default instance of *Server.
@@ -247,6 +258,19 @@ func (server *Server) OnConnInit(handler ConnHandler) {
 	server.onConnInit = handler
 }
 
+// SetStreamWindow sets the number of chunks a stream may send before the
+// client grants credits, DefaultStreamWindow if not set.
+func (server *Server) SetStreamWindow(window int) {
+	server.window = window
+}
+
+func (server *Server) streamWindow() int {
+	if server.window > 0 {
+		return server.window
+	}
+	return DefaultStreamWindow
+}
+
 // OnMissingMethod ...
 func (server *Server) OnMissingMethod(handler MissingMethodFunc) {
 	server.onMissingMethod = handler
@@ -401,7 +425,8 @@ func suitableMethods(typ reflect.Type, reportErr bool) map[string]*methodType {
 			}
 			continue
 		}
-		methods[mname] = &methodType{method: method, ArgType: argType, ReplyType: replyType}
+		methods[mname] = &methodType{method: method, ArgType: argType, ReplyType: replyType,
+			stream: replyType == typeOfStream}
 	}
 	return methods
 }
@@ -450,6 +475,13 @@ func (s *service) call(conn *Conn, args *Args) (resp interface{}, err error) {
 		err = errInter.(error)
 	}
 
+	if args.mType.stream {
+		stream := args.Reply.Interface().(*Stream)
+		conn.closeStream(stream)
+		resp = stream.summary()
+		return
+	}
+
 	resp = args.Reply.Interface()
 	return
 }
@@ -459,6 +491,9 @@ func (s *service) call(conn *Conn, args *Args) (resp interface{}, err error) {
 func (server *Server) ServeCodec(req *http.Request, codec ServerCodec, onInit ConnHandler) {
 	sending := new(sync.Mutex)
 	conn := NewConn(req, sending, codec)
+	server.addConn(conn)
+	defer server.removeConn(conn)
+
 	if server.onConnInit != nil {
 		server.onConnInit(conn)
 	}
@@ -480,14 +515,32 @@ func (server *Server) ServeCodec(req *http.Request, codec ServerCodec, onInit Co
 		args.Method = codec.GetMethod()
 
 		if err == nil {
+			if args.mType.stream {
+				stream, err := conn.openStream(req.Seq, server.streamWindow())
+				if err != nil {
+					server.sendResponse(sending, req, invalidRequest, codec, err)
+					server.freeRequest(req)
+					continue
+				}
+				args.Reply = reflect.ValueOf(stream)
+			}
+
+			atomic.AddInt64(&conn.inflight, 1)
 			go func() {
 				var (
 					reply interface{}
 					err   error
 				)
+				defer atomic.AddInt64(&conn.inflight, -1)
+				if args.mType.stream {
+					// the wrapper may return without calling the method
+					defer conn.closeStream(args.Reply.Interface().(*Stream))
+				}
 
 				if server.onWrap != nil {
 					reply, err = server.onWrap(service.call)(conn, args)
+				} else {
+					reply, err = service.call(conn, args)
 				}
 
 				server.sendResponse(sending, req, reply, codec, err)
@@ -498,8 +551,17 @@ func (server *Server) ServeCodec(req *http.Request, codec ServerCodec, onInit Co
 
 		switch err.(type) {
 		case ErrMissingServiceMethod:
+			if args.Method == CreditMethod {
+				err = conn.credit(args.RawReq)
+				server.sendResponse(sending, req, true, codec, err)
+				server.freeRequest(req)
+				continue
+			}
+
 			if server.onMissingMethod != nil {
+				atomic.AddInt64(&conn.inflight, 1)
 				go func() {
+					defer atomic.AddInt64(&conn.inflight, -1)
 					reply, err := server.onMissingMethod(conn, args.Method, args.RawReq)
 					server.sendResponse(sending, req, reply, codec, err)
 					server.freeRequest(req)
@@ -524,6 +586,43 @@ func (server *Server) ServeCodec(req *http.Request, codec ServerCodec, onInit Co
 	sending.Unlock()
 }
 
+func (server *Server) addConn(conn *Conn) {
+	server.connLock.Lock()
+	defer server.connLock.Unlock()
+	if server.conns == nil {
+		server.conns = make(map[uint64]*Conn)
+	}
+	server.connSeq++
+	conn.id = server.connSeq
+	server.conns[conn.id] = conn
+}
+
+func (server *Server) removeConn(conn *Conn) {
+	server.connLock.Lock()
+	delete(server.conns, conn.id)
+	server.connLock.Unlock()
+}
+
+// Conns returns the live connections ordered by id.
+func (server *Server) Conns() []*Conn {
+	server.connLock.RLock()
+	conns := make([]*Conn, 0, len(server.conns))
+	for _, conn := range server.conns {
+		conns = append(conns, conn)
+	}
+	server.connLock.RUnlock()
+
+	sort.Slice(conns, func(i, j int) bool { return conns[i].id < conns[j].id })
+	return conns
+}
+
+// Conn returns the live connection with id, nil if not found.
+func (server *Server) Conn(id uint64) *Conn {
+	server.connLock.RLock()
+	defer server.connLock.RUnlock()
+	return server.conns[id]
+}
+
 func (server *Server) getRequest() *Request {
 	server.reqLock.Lock()
 	req := server.freeReq
@@ -0,0 +1,58 @@
+package wsrpc_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+)
+
+type AddReq struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+type Adder struct{}
+
+func (a *Adder) Add(conn *wsrpc.Conn, req *AddReq, rsp *int) error {
+	*rsp = req.A + req.B
+	return nil
+}
+
+func TestServeWithoutWrap(t *testing.T) {
+	srv := wsrpc.NewServer()
+	if err := srv.RegisterName("Adder", &Adder{}); err != nil {
+		t.Fatal(err)
+	}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go srv.ServeCodec(httptest.NewRequest(http.MethodGet, wsrpc.DefaultRPCPath, nil),
+		wsrpc.NewServerCodec(server), nil)
+
+	client.SetDeadline(time.Now().Add(time.Second))
+	if err := json.NewEncoder(client).Encode(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "Adder.Add",
+		"params":  &AddReq{A: 1, B: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var rsp struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  interface{}     `json:"error"`
+	}
+	if err := json.NewDecoder(client).Decode(&rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.ID != 1 || string(rsp.Result) != "3" || rsp.Error != nil {
+		t.Fatalf("unexpected response %+v", rsp)
+	}
+}
@@ -0,0 +1,188 @@
+package wsrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// Stream methods
+const (
+	// StreamMethod is the notification carrying a chunk of a stream.
+	StreamMethod = "rpc.stream"
+	// CreditMethod is called by the client to grant more chunks to a stream.
+	CreditMethod = "rpc.credit"
+)
+
+// DefaultStreamWindow is the number of chunks a stream may send before the
+// client has to grant credits.
+var DefaultStreamWindow = 16
+
+// ErrStreamClosed is returned by Send once the stream is finished or the
+// connection is closed.
+var ErrStreamClosed = errors.New("rpc: stream closed")
+
+// Stream lets a method send its result in chunks. A streaming method looks
+// schematically like
+//
+//	func (t *T) MethodName(conn *Conn, argType T1, stream *Stream) error
+//
+// every chunk is pushed as a StreamMethod notification tied to the request
+// id, followed by the response whose result is a StreamSummary.
+type Stream struct {
+	conn *Conn
+	id   json.RawMessage
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	credits int
+	seq     uint64
+	closed  bool
+	result  interface{}
+}
+
+// StreamChunk is the params of a StreamMethod notification.
+type StreamChunk struct {
+	ID   json.RawMessage `json:"id"`
+	Seq  uint64          `json:"seq"`
+	Data interface{}     `json:"data"`
+}
+
+// StreamSummary is the result of a streaming method.
+type StreamSummary struct {
+	Chunks uint64      `json:"chunks"`
+	Result interface{} `json:"result,omitempty"`
+}
+
+// StreamCredit is the params of a CreditMethod call.
+type StreamCredit struct {
+	ID      json.RawMessage `json:"id"`
+	Credits int             `json:"credits"`
+}
+
+type requestIDer interface {
+	RequestID(seq uint64) json.RawMessage
+}
+
+func newStream(conn *Conn, id json.RawMessage, window int) *Stream {
+	s := &Stream{
+		conn:    conn,
+		id:      id,
+		credits: window,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// ID returns the request id the stream is tied to.
+func (s *Stream) ID() json.RawMessage {
+	return s.id
+}
+
+// Send pushes a chunk to the client, it blocks while the stream has no
+// credits left.
+func (s *Stream) Send(x interface{}) error {
+	s.mu.Lock()
+	for s.credits <= 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		s.mu.Unlock()
+		return ErrStreamClosed
+	}
+	s.credits--
+	s.seq++
+	seq := s.seq
+	s.mu.Unlock()
+
+	return s.conn.NotifyEx(StreamMethod, &StreamChunk{
+		ID:   s.id,
+		Seq:  seq,
+		Data: x,
+	})
+}
+
+// SetResult attaches x to the summary sent when the method returns.
+func (s *Stream) SetResult(x interface{}) {
+	s.mu.Lock()
+	s.result = x
+	s.mu.Unlock()
+}
+
+func (s *Stream) credit(n int) {
+	s.mu.Lock()
+	s.credits += n
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func (s *Stream) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+func (s *Stream) summary() *StreamSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &StreamSummary{
+		Chunks: s.seq,
+		Result: s.result,
+	}
+}
+
+// openStream registers the stream of the request seq, failing if the client
+// reused the id of a stream still open.
+func (c *Conn) openStream(seq uint64, window int) (*Stream, error) {
+	var id json.RawMessage
+	if ider, ok := c.codec.(requestIDer); ok {
+		id = ider.RequestID(seq)
+	}
+	if id == nil {
+		id = json.RawMessage(strconv.FormatUint(seq, 10))
+	}
+
+	stream := newStream(c, id, window)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.streams[string(id)]; ok {
+		return nil, errors.New("rpc: duplicate stream " + string(id))
+	}
+	if c.closed {
+		stream.closed = true
+	}
+	c.streams[string(id)] = stream
+	return stream, nil
+}
+
+func (c *Conn) closeStream(stream *Stream) {
+	stream.close()
+
+	c.mu.Lock()
+	if c.streams[string(stream.id)] == stream {
+		delete(c.streams, string(stream.id))
+	}
+	c.mu.Unlock()
+}
+
+func (c *Conn) credit(params json.RawMessage) error {
+	var credit StreamCredit
+	if err := json.Unmarshal(params, &credit); err != nil {
+		return err
+	}
+	if credit.Credits <= 0 {
+		return errors.New("rpc: credits must be positive")
+	}
+
+	c.mu.RLock()
+	stream := c.streams[string(credit.ID)]
+	c.mu.RUnlock()
+	if stream == nil {
+		return errors.New("rpc: unknown stream " + string(credit.ID))
+	}
+
+	stream.credit(credit.Credits)
+	return nil
+}
@@ -0,0 +1,145 @@
+package wsrpc_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+	"github.com/hkjojo/go-toolkits/wsrpc/wsrpctest"
+)
+
+type ListReq struct {
+	Count int `json:"count"`
+}
+
+type Lister struct{}
+
+func (l *Lister) List(conn *wsrpc.Conn, req *ListReq, stream *wsrpc.Stream) error {
+	for i := 0; i < req.Count; i++ {
+		if err := stream.Send(i); err != nil {
+			return err
+		}
+	}
+	stream.SetResult("done")
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	srv := wsrpc.NewServer()
+	srv.SetStreamWindow(2)
+	if err := srv.RegisterName("Lister", &Lister{}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := wsrpctest.NewClient(srv)
+	defer c.Close()
+
+	var (
+		summary wsrpc.StreamSummary
+		done    = make(chan error, 1)
+	)
+	go func() {
+		done <- c.Call("Lister.List", &ListReq{Count: 3}, &summary)
+	}()
+
+	for i := 0; i < 2; i++ {
+		var chunk struct {
+			ID   int `json:"id"`
+			Seq  int `json:"seq"`
+			Data int `json:"data"`
+		}
+		n := c.ExpectNotification(t, wsrpc.StreamMethod, time.Second)
+		if err := n.Decode(&chunk); err != nil {
+			t.Fatal(err)
+		}
+		if chunk.ID != 1 || chunk.Seq != i+1 || chunk.Data != i {
+			t.Fatalf("unexpected chunk %s", n.Params)
+		}
+	}
+
+	// the window is used up until credits are granted
+	c.ExpectNoNotification(t, 20*time.Millisecond)
+	for _, credits := range []int{0, -1} {
+		err := c.Call(wsrpc.CreditMethod, map[string]int{"id": 1, "credits": credits}, nil)
+		if _, ok := err.(wsrpctest.ServerError); !ok {
+			t.Fatalf("expected credits error for %d get:%v", credits, err)
+		}
+	}
+	c.ExpectNoNotification(t, 20*time.Millisecond)
+	if err := c.Call(wsrpc.CreditMethod, map[string]int{"id": 1, "credits": 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.ExpectNotification(t, wsrpc.StreamMethod, time.Second)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if summary.Chunks != 3 || summary.Result != "done" {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	err := c.Call(wsrpc.CreditMethod, map[string]int{"id": 1, "credits": 1}, nil)
+	if _, ok := err.(wsrpctest.ServerError); !ok {
+		t.Fatalf("expected unknown stream error get:%v", err)
+	}
+}
+
+func TestStreamClosedConn(t *testing.T) {
+	srv := wsrpc.NewServer()
+	srv.SetStreamWindow(1)
+	srv.RegisterName("Lister", &Lister{})
+
+	c := wsrpctest.NewClient(srv)
+	go c.Call("Lister.List", &ListReq{Count: 10}, nil)
+	c.ExpectNotification(t, wsrpc.StreamMethod, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("blocked stream not released on close")
+	}
+}
+
+func TestStreamDuplicateID(t *testing.T) {
+	srv := wsrpc.NewServer()
+	srv.SetStreamWindow(1)
+	srv.RegisterName("Lister", &Lister{})
+
+	c := wsrpctest.NewClient(srv)
+	defer c.Close()
+
+	// a hand-written request takes the id of the next call
+	if err := c.Send(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "Lister.List",
+		"params":  &ListReq{Count: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	c.ExpectNotification(t, wsrpc.StreamMethod, time.Second)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Call("Lister.List", &ListReq{Count: 2}, nil)
+	}()
+	select {
+	case err := <-done:
+		if _, ok := err.(wsrpctest.ServerError); !ok {
+			t.Fatalf("expected duplicate stream error get:%v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("duplicate stream opened")
+	}
+
+	// the first stream is still open
+	if err := c.Call(wsrpc.CreditMethod, map[string]int{"id": 1, "credits": 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.ExpectNotification(t, wsrpc.StreamMethod, time.Second)
+}
@@ -0,0 +1,268 @@
+package wsrpctest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+)
+
+// ErrShutdown is returned by Call once the connection has been closed.
+var ErrShutdown = errors.New("wsrpctest: connection is shut down")
+
+// ServerError represents an error that has been returned from
+// the remote side of the RPC connection.
+type ServerError string
+
+func (e ServerError) Error() string {
+	return string(e)
+}
+
+// Notification is a notification frame pushed by the server.
+type Notification struct {
+	Method string
+	Params json.RawMessage
+}
+
+type clientRequest struct {
+	Version string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type clientResponse struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params json.RawMessage  `json:"params"`
+	Result json.RawMessage  `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+// Option ...
+type Option func(*options)
+
+type options struct {
+	req      *http.Request
+	onInit   wsrpc.ConnHandler
+	recorder *Recorder
+	buffer   int
+}
+
+// WithRequest sets the http request the server sees for the connection.
+func WithRequest(req *http.Request) Option {
+	return func(o *options) {
+		o.req = req
+	}
+}
+
+// WithOnInit sets the init handler passed to ServeCodec.
+func WithOnInit(handler wsrpc.ConnHandler) Option {
+	return func(o *options) {
+		o.onInit = handler
+	}
+}
+
+// WithRecorder records every frame exchanged by the client.
+func WithRecorder(r *Recorder) Option {
+	return func(o *options) {
+		o.recorder = r
+	}
+}
+
+// WithNotificationBuffer sets how many unread notifications are buffered
+// before the server blocks on writing, default 1024.
+func WithNotificationBuffer(n int) Option {
+	return func(o *options) {
+		o.buffer = n
+	}
+}
+
+// Client is a JSON-RPC client connected to a Server through an
+// in-memory pipe.
+type Client struct {
+	conn     net.Conn
+	enc      *json.Encoder
+	recorder *Recorder
+
+	sending sync.Mutex // protects enc
+	mu      sync.Mutex // protects seq, pending, closed
+	seq     uint64
+	pending map[uint64]chan *clientResponse
+	closed  bool
+
+	notifications chan *Notification
+	served        chan struct{}
+	done          chan struct{}
+}
+
+// NewClient serves a new in-memory connection on server and returns the
+// client side of it. The connection is served until Close is called.
+func NewClient(server *wsrpc.Server, opts ...Option) *Client {
+	o := &options{buffer: 1024}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.req == nil {
+		o.req = NewRequest()
+	}
+
+	codec, conn := NewPipe()
+	c := &Client{
+		conn:          conn,
+		enc:           json.NewEncoder(conn),
+		recorder:      o.recorder,
+		pending:       make(map[uint64]chan *clientResponse),
+		notifications: make(chan *Notification, o.buffer),
+		served:        make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go func() {
+		server.ServeCodec(o.req, codec, o.onInit)
+		close(c.served)
+	}()
+	go c.loop()
+	return c
+}
+
+func (c *Client) loop() {
+	dec := json.NewDecoder(c.conn)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			break
+		}
+		c.recorder.record(Received, raw)
+
+		var rsp clientResponse
+		if err := json.Unmarshal(raw, &rsp); err != nil {
+			continue
+		}
+
+		if rsp.ID == nil && rsp.Method != "" {
+			select {
+			case c.notifications <- &Notification{
+				Method: rsp.Method,
+				Params: rsp.Params,
+			}:
+			case <-c.done:
+			}
+			continue
+		}
+
+		if rsp.ID == nil {
+			continue
+		}
+		seq, err := strconv.ParseUint(string(*rsp.ID), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		call, ok := c.pending[seq]
+		delete(c.pending, seq)
+		c.mu.Unlock()
+		if ok {
+			call <- &rsp
+		}
+	}
+
+	c.mu.Lock()
+	c.closed = true
+	for seq, call := range c.pending {
+		delete(c.pending, seq)
+		close(call)
+	}
+	c.mu.Unlock()
+	close(c.notifications)
+}
+
+// Call invokes the named method, waits for it to complete and unmarshals
+// the result into reply, which may be nil to discard it.
+func (c *Client) Call(method string, params, reply interface{}) error {
+	if params == nil {
+		params = struct{}{}
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrShutdown
+	}
+	c.seq++
+	seq := c.seq
+	call := make(chan *clientResponse, 1)
+	c.pending[seq] = call
+	c.mu.Unlock()
+
+	if err := c.Send(&clientRequest{
+		Version: "2.0",
+		ID:      seq,
+		Method:  method,
+		Params:  params,
+	}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, seq)
+		c.mu.Unlock()
+		return err
+	}
+
+	rsp, ok := <-call
+	if !ok {
+		return ErrShutdown
+	}
+	if rsp.Error != nil {
+		return ServerError(fmt.Sprint(rsp.Error))
+	}
+	if reply == nil || len(rsp.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(rsp.Result, reply)
+}
+
+// Send writes x as a raw frame, which lets tests send malformed or
+// hand-written requests.
+func (c *Client) Send(x interface{}) error {
+	data, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+
+	c.sending.Lock()
+	defer c.sending.Unlock()
+	c.recorder.record(Sent, data)
+	return c.enc.Encode(json.RawMessage(data))
+}
+
+// Notifications returns the channel of notifications pushed by the server,
+// it is closed once the connection is closed.
+func (c *Client) Notifications() <-chan *Notification {
+	return c.notifications
+}
+
+// Close closes the client side of the pipe and waits until the server has
+// finished serving the connection, so close handlers have run on return.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+	c.mu.Unlock()
+
+	err := c.conn.Close()
+	<-c.served
+	if err == io.ErrClosedPipe {
+		err = nil
+	}
+	return err
+}
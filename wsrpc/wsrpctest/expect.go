@@ -0,0 +1,78 @@
+package wsrpctest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// DefaultTimeout used by ExpectNotification when timeout is zero.
+var DefaultTimeout = time.Second
+
+// ExpectNotification waits for the next notification and fails the test if
+// none arrives within timeout or its method is not the expected one.
+func (c *Client) ExpectNotification(t testing.TB, method string, timeout time.Duration) *Notification {
+	t.Helper()
+
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case n, ok := <-c.notifications:
+		if !ok {
+			t.Fatalf("connection closed while waiting for notification %s", method)
+			return nil
+		}
+		if n.Method != method {
+			t.Fatalf("expected notification %s, get %s: %s", method, n.Method, n.Params)
+		}
+		return n
+	case <-timer.C:
+		t.Fatalf("timeout after %v waiting for notification %s", timeout, method)
+	}
+	return nil
+}
+
+// ExpectNotifications waits for the given methods in order, each within
+// timeout of the previous one.
+func (c *Client) ExpectNotifications(t testing.TB, timeout time.Duration, methods ...string) []*Notification {
+	t.Helper()
+
+	ns := make([]*Notification, 0, len(methods))
+	for _, method := range methods {
+		ns = append(ns, c.ExpectNotification(t, method, timeout))
+	}
+	return ns
+}
+
+// ExpectNoNotification fails the test if a notification arrives within d.
+func (c *Client) ExpectNoNotification(t testing.TB, d time.Duration) {
+	t.Helper()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case n, ok := <-c.notifications:
+		if ok {
+			t.Fatalf("unexpected notification %s: %s", n.Method, n.Params)
+		}
+	case <-timer.C:
+	}
+}
+
+// Decode unmarshals the notification params into x. Params sent with
+// Conn.Notify are wrapped in a one element array, which is unwrapped.
+func (n *Notification) Decode(x interface{}) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(n.Params, &params); err == nil && len(params) == 1 {
+		if err = json.Unmarshal(params[0], x); err == nil {
+			return nil
+		}
+	}
+	return json.Unmarshal(n.Params, x)
+}
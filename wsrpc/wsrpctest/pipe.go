@@ -0,0 +1,24 @@
+// Package wsrpctest provides utilities for testing wsrpc services without
+// an HTTP server or a real websocket upgrade.
+package wsrpctest
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+)
+
+// NewPipe returns a ServerCodec backed by one end of an in-memory pipe and
+// the other end of that pipe, which plays the role of the websocket client.
+func NewPipe() (wsrpc.ServerCodec, net.Conn) {
+	server, client := net.Pipe()
+	return wsrpc.NewServerCodec(server), client
+}
+
+// NewRequest returns the http request handed to wsrpc.Server.ServeCodec when
+// no request is supplied with WithRequest.
+func NewRequest() *http.Request {
+	return httptest.NewRequest(http.MethodGet, wsrpc.DefaultRPCPath, nil)
+}
@@ -0,0 +1,113 @@
+package wsrpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Update rewrites golden files instead of comparing against them,
+// run tests with -wsrpctest.update to refresh them.
+var Update = flag.Bool("wsrpctest.update", false, "update wsrpctest golden files")
+
+// Direction of a recorded frame.
+type Direction string
+
+// Directions
+const (
+	Sent     Direction = ">"
+	Received Direction = "<"
+)
+
+// Frame is one JSON value written to or read from the connection.
+type Frame struct {
+	Direction Direction
+	Data      json.RawMessage
+}
+
+// Recorder captures every frame exchanged by a Client.
+type Recorder struct {
+	mu     sync.Mutex
+	frames []Frame
+}
+
+// NewRecorder ...
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(dir Direction, data []byte) {
+	if r == nil {
+		return
+	}
+
+	// compact so the output does not depend on encoder whitespace
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		buf.Reset()
+		buf.Write(data)
+	}
+
+	r.mu.Lock()
+	r.frames = append(r.frames, Frame{Direction: dir, Data: buf.Bytes()})
+	r.mu.Unlock()
+}
+
+// Frames returns a copy of the recorded frames.
+func (r *Recorder) Frames() []Frame {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	frames := make([]Frame, len(r.frames))
+	copy(frames, r.frames)
+	return frames
+}
+
+// Reset drops every recorded frame.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.frames = nil
+	r.mu.Unlock()
+}
+
+// Bytes renders the frames one per line, prefixed by their direction.
+func (r *Recorder) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, f := range r.Frames() {
+		buf.WriteString(string(f.Direction))
+		buf.WriteByte(' ')
+		buf.Write(f.Data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// AssertGolden compares the recorded frames with the golden file at path,
+// or writes it when -wsrpctest.update is set.
+func (r *Recorder) AssertGolden(t testing.TB, path string) {
+	t.Helper()
+
+	got := r.Bytes()
+	if *Update {
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file: %v (run with -wsrpctest.update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("frames mismatch golden file %s\nexpected:\n%s\nget:\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,100 @@
+package wsrpctest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hkjojo/go-toolkits/wsrpc"
+)
+
+type EchoReq struct {
+	Text  string `json:"text"`
+	Times int    `json:"times"`
+}
+
+type EchoRsp struct {
+	Text string `json:"text"`
+}
+
+type Echo struct{}
+
+func (e *Echo) Say(conn *wsrpc.Conn, req *EchoReq, rsp *EchoRsp) error {
+	if req.Text == "" {
+		return errors.New("empty text")
+	}
+	for i := 0; i < req.Times; i++ {
+		conn.Notify("Echo.OnSay", &EchoRsp{Text: req.Text})
+	}
+	rsp.Text = req.Text
+	return nil
+}
+
+func newServer(t *testing.T) *wsrpc.Server {
+	srv := wsrpc.NewServer()
+	if err := srv.RegisterName("Echo", &Echo{}); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestClientCall(t *testing.T) {
+	var closed = make(chan struct{})
+	c := NewClient(newServer(t), WithOnInit(func(conn *wsrpc.Conn) {
+		conn.OnClose(func() { close(closed) })
+	}))
+
+	var rsp EchoRsp
+	if err := c.Call("Echo.Say", &EchoReq{Text: "hi", Times: 2}, &rsp); err != nil {
+		t.Fatal(err)
+	}
+	if rsp.Text != "hi" {
+		t.Fatalf("expected:hi get:%s", rsp.Text)
+	}
+
+	for _, n := range c.ExpectNotifications(t, time.Second, "Echo.OnSay", "Echo.OnSay") {
+		var v EchoRsp
+		if err := n.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		if v.Text != "hi" {
+			t.Fatalf("expected:hi get:%s", v.Text)
+		}
+	}
+	c.ExpectNoNotification(t, 10*time.Millisecond)
+
+	err := c.Call("Echo.Say", &EchoReq{}, nil)
+	if _, ok := err.(ServerError); !ok || err.Error() != "empty text" {
+		t.Fatalf("expected server error get:%v", err)
+	}
+
+	err = c.Call("Echo.Missing", nil, nil)
+	if _, ok := err.(ServerError); !ok {
+		t.Fatalf("expected server error get:%v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closed:
+	default:
+		t.Fatal("close handler not called")
+	}
+	if err := c.Call("Echo.Say", &EchoReq{Text: "hi"}, nil); err != ErrShutdown {
+		t.Fatalf("expected:%v get:%v", ErrShutdown, err)
+	}
+}
+
+func TestRecorderGolden(t *testing.T) {
+	rec := NewRecorder()
+	c := NewClient(newServer(t), WithRecorder(rec))
+	defer c.Close()
+
+	if err := c.Call("Echo.Say", &EchoReq{Text: "golden", Times: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.ExpectNotification(t, "Echo.OnSay", 0)
+
+	rec.AssertGolden(t, "testdata/echo.golden")
+}